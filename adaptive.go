@@ -0,0 +1,109 @@
+package jrc
+
+import (
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+)
+
+//adaptiveBatch tunes the batch size from the calls made: halved when a call times out or is too large, grown while calls are fast
+type adaptiveBatch struct {
+	mu     sync.Mutex
+	min    int
+	max    int
+	target time.Duration //calls quicker than this grow the size, slower ones shrink it
+	size   int
+}
+
+func (a *adaptiveBatch) current() int {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.size
+}
+
+//observe adjusts the size after an HTTP call of n requests that took d and failed with err, or nil
+//  changes are made relative to n, so the many calls of one batch don't compound each other
+func (a *adaptiveBatch) observe(n int, d time.Duration, err error) {
+	if n < 1 {
+		return
+	}
+	var se *StatusError
+	tooLarge := errors.As(err, &se) && se.StatusCode == http.StatusRequestEntityTooLarge
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	switch {
+	case tooLarge:
+		//the server refuses batches this big, so don't grow back into it
+		if n-1 < a.max {
+			a.max = n - 1
+		}
+		if a.max < a.min {
+			a.max = a.min
+		}
+		a.shrink(n / 2)
+	case isTimeout(err):
+		a.shrink(n / 2)
+	case err != nil:
+		//says nothing about the size of the batch
+		return
+	case d > a.target:
+		a.shrink(n - (n+3)/4)
+	case n >= a.size:
+		//only a full call shows the current size is fast enough
+		a.size = n + (n+7)/8
+	}
+	if a.size < a.min {
+		a.size = a.min
+	}
+	if a.size > a.max {
+		a.size = a.max
+	}
+}
+
+//shrink lowers the size to n unless it is already smaller, the caller holding a.mu
+func (a *adaptiveBatch) shrink(n int) {
+	if n < a.size {
+		a.size = n
+	}
+}
+
+//batchSize is the number of requests to put in each HTTP call, MaxBatch unless it is being adapted
+func (srv *Server) batchSize() int {
+	if srv.adapt != nil {
+		return srv.adapt.current()
+	}
+	return srv.batch
+}
+
+func (srv *Server) setAdaptiveBatch(min, max int, target time.Duration) error {
+	if min == 0 && max == 0 {
+		srv.adapt = nil
+		return nil
+	}
+	if min < 1 || max < min {
+		return errors.New("jrc: AdaptiveBatch needs 1 <= min <= max")
+	}
+	if target <= 0 {
+		return errors.New("jrc: AdaptiveBatch target latency must be positive")
+	}
+	size := srv.batch
+	if size < min {
+		size = min
+	}
+	if size > max {
+		size = max
+	}
+	srv.adapt = &adaptiveBatch{min: min, max: max, target: target, size: size}
+	return nil
+}
+
+//AdaptiveBatch varies the batch size between min and max instead of keeping to MaxBatch, which it starts from
+//  the size is halved when a call times out or gets HTTP 413, shrunk by a quarter when a call takes longer than target,
+//  and grown by an eighth when a full-sized call is quicker; a 413 also keeps it below the size refused from then on
+//  zero for both min and max goes back to MaxBatch
+func AdaptiveBatch(min, max int, target time.Duration) func(server *Server) error {
+	return func(srv *Server) error {
+		return srv.setAdaptiveBatch(min, max, target)
+	}
+}
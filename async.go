@@ -0,0 +1,39 @@
+package jrc
+
+import "context"
+
+//Result is a response received by ExecAsync, or the error that ended the batch
+type Result struct {
+	Response RpcResponse
+	Err      error //set only on the last Result sent when the batch fails
+}
+
+//ExecAsync executes a batch of calls in the background, sending each response on the returned channel as it arrives
+//  the channel is closed once the batch is done; new calls wait while results are unread, so reading slowly applies backpressure
+func (srv *Server) ExecAsync(rs RPCRequests, opts ...CallOption) <-chan Result {
+	return srv.ExecAsyncContext(context.Background(), rs, opts...)
+}
+
+//ExecAsyncContext is ExecAsync with a context to cancel the batch or apply a deadline
+//  cancel ctx when giving up on the channel before it is closed, so the batch can stop
+func (srv *Server) ExecAsyncContext(ctx context.Context, rs RPCRequests, opts ...CallOption) <-chan Result {
+	out := make(chan Result)
+	go func() {
+		defer close(out)
+		err := srv.ExecBatchStreamContext(ctx, rs, func(resp RpcResponse) error {
+			select {
+			case out <- Result{Response: resp}:
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}, opts...)
+		if err != nil {
+			select {
+			case out <- Result{Err: err}:
+			case <-ctx.Done():
+			}
+		}
+	}()
+	return out
+}
@@ -0,0 +1,57 @@
+package jrc
+
+import (
+	"context"
+	"encoding/base64"
+
+	"golang.org/x/oauth2"
+)
+
+//BasicAuth sets the Authorization header on every request using HTTP basic authentication
+func BasicAuth(user, pass string) func(server *Server) error {
+	return func(srv *Server) error {
+		return srv.addHeader("Authorization", "Basic "+base64.StdEncoding.EncodeToString([]byte(user+":"+pass)))
+	}
+}
+
+//BearerToken sets the Authorization header on every request to a fixed bearer token
+func BearerToken(token string) func(server *Server) error {
+	return func(srv *Server) error {
+		return srv.addHeader("Authorization", "Bearer "+token)
+	}
+}
+
+func (srv *Server) setAuth(f func(ctx context.Context) (string, error)) error {
+	srv.auth = f
+	return nil
+}
+
+//BearerTokenFunc calls f for a bearer token before each HTTP call is dispatched
+//  f should cache the token and only refresh it when it is close to expiring, as it is called for every sub-batch
+//  an error from f fails the call before anything is sent
+func BearerTokenFunc(f func(ctx context.Context) (string, error)) func(server *Server) error {
+	return func(srv *Server) error {
+		return srv.setAuth(func(ctx context.Context) (string, error) {
+			token, err := f(ctx)
+			if err != nil {
+				return "", err
+			}
+			return "Bearer " + token, nil
+		})
+	}
+}
+
+//OAuth2 attaches an access token from ts to every HTTP call
+//  ts is wrapped with oauth2.ReuseTokenSource so tokens are cached and only refreshed once they expire
+func OAuth2(ts oauth2.TokenSource) func(server *Server) error {
+	ts = oauth2.ReuseTokenSource(nil, ts)
+	return func(srv *Server) error {
+		return srv.setAuth(func(ctx context.Context) (string, error) {
+			tok, err := ts.Token()
+			if err != nil {
+				return "", err
+			}
+			return tok.Type() + " " + tok.AccessToken, nil
+		})
+	}
+}
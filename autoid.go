@@ -0,0 +1,119 @@
+package jrc
+
+import (
+	"errors"
+	"strconv"
+)
+
+//AutoID gives requests with a zero Id and no StrId the next id from the Server's IDGenerator, SequentialIDs unless set by GenerateIDs
+//  ids already used by other requests in the same batch are skipped, and the assigned Id is written back to the request
+func AutoID(b bool) func(server *Server) error {
+	return func(srv *Server) error {
+		srv.autoID = b
+		return nil
+	}
+}
+
+//assignIDs fills in the unset ids of rs when AutoID is on, then deals with those used twice as CheckIDs says
+func (srv *Server) assignIDs(rs RPCRequests) error {
+	if srv.autoID {
+		srv.fillIDs(rs)
+	}
+	if srv.idCheck == AllowDuplicates || len(rs) < 2 {
+		return nil
+	}
+	first := make(map[ID]int, len(rs))
+	dups := false
+	for i, r := range rs {
+		if !r.hasID() {
+			continue
+		}
+		j, dup := first[r.ID()]
+		if !dup {
+			first[r.ID()] = i
+			continue
+		}
+		if srv.idCheck == RejectDuplicates {
+			return &DuplicateIDError{ID: r.ID(), First: j, Second: i}
+		}
+		dups = true
+	}
+	if !dups {
+		return nil
+	}
+	//every id is known by now, so a new one cannot take that of a request further on
+	for i, r := range rs {
+		if !r.hasID() || first[r.ID()] == i {
+			continue
+		}
+		id := srv.ids.NextID()
+		for _, used := first[id]; used; _, used = first[id] {
+			id = srv.ids.NextID()
+		}
+		r.SetID(id)
+		first[id] = i
+	}
+	return nil
+}
+
+//IDCheck selects what is done about a batch in which several requests have the same id, see CheckIDs
+type IDCheck int
+
+const (
+	//AllowDuplicates sends the batch as it is, Correlate and PreserveOrder pairing the responses in the order they come
+	AllowDuplicates IDCheck = iota
+	//RejectDuplicates fails the batch with a *DuplicateIDError before anything is sent
+	RejectDuplicates
+	//RenumberDuplicates gives each request after the first with an id a new one from the IDGenerator, written back to it
+	RenumberDuplicates
+)
+
+func (srv *Server) setIDCheck(c IDCheck) error {
+	if c < AllowDuplicates || c > RenumberDuplicates {
+		return errors.New("jrc: unknown id check")
+	}
+	srv.idCheck = c
+	return nil
+}
+
+//CheckIDs looks for requests sharing an id in every batch before it is sent, which makes their responses impossible
+//  to tell apart and which some servers refuse; requests without an id are left to AutoID, AllowDuplicates by default
+func CheckIDs(c IDCheck) func(server *Server) error {
+	return func(srv *Server) error {
+		return srv.setIDCheck(c)
+	}
+}
+
+//DuplicateIDError is a batch refused by RejectDuplicates
+type DuplicateIDError struct {
+	ID            ID
+	First, Second int //positions in the batch of the first two requests with the id
+}
+
+//Error implements the error interface
+func (e *DuplicateIDError) Error() string {
+	return "jrc: requests " + strconv.Itoa(e.First) + " and " + strconv.Itoa(e.Second) + " share the id " + e.ID.String()
+}
+
+//fillIDs gives every request in rs without an id one from the Server's IDGenerator that is unique within rs
+func (srv *Server) fillIDs(rs RPCRequests) {
+	var used map[ID]bool
+	for _, r := range rs {
+		if r.hasID() {
+			if used == nil {
+				used = make(map[ID]bool, len(rs))
+			}
+			used[r.ID()] = true
+		}
+	}
+	for _, r := range rs {
+		if r.hasID() {
+			continue
+		}
+		id := srv.ids.NextID()
+		for used[id] {
+			id = srv.ids.NextID()
+		}
+		r.SetID(id)
+	}
+}
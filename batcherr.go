@@ -0,0 +1,56 @@
+package jrc
+
+import (
+	"strconv"
+)
+
+//CallError is an HTTP call of a batch that failed at the transport level, with the requests it carried
+//  Requests is nil for notifications
+type CallError struct {
+	SubBatch
+	Err error
+}
+
+//Error implements the error interface
+func (e *CallError) Error() string {
+	return "jrc: call " + strconv.Itoa(e.Index) + " failed: " + e.Err.Error()
+}
+
+//Unwrap returns the transport error
+func (e *CallError) Unwrap() error {
+	return e.Err
+}
+
+//BatchError is returned when some of the HTTP calls of a batch fail
+//  the responses from the calls that succeeded are returned alongside it
+type BatchError struct {
+	Calls  int         //the number of HTTP calls made for the batch
+	Failed []CallError //the calls that failed, in order
+}
+
+//Error implements the error interface
+func (e *BatchError) Error() string {
+	msg := "jrc: " + strconv.Itoa(len(e.Failed)) + " of " + strconv.Itoa(e.Calls) + " calls failed"
+	if len(e.Failed) > 0 {
+		msg += ", first: " + e.Failed[0].Err.Error()
+	}
+	return msg
+}
+
+//FailedRequests returns the requests carried by the failed calls, for retrying just those
+func (e *BatchError) FailedRequests() RPCRequests {
+	var rs RPCRequests
+	for _, f := range e.Failed {
+		rs = append(rs, f.Requests...)
+	}
+	return rs
+}
+
+//attribute fills in the sub-batches of the failed calls if err is a *BatchError
+func attribute(err error, subs []SubBatch) {
+	if be, ok := err.(*BatchError); ok {
+		for k := range be.Failed {
+			be.Failed[k].SubBatch = subs[be.Failed[k].Index]
+		}
+	}
+}
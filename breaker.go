@@ -0,0 +1,147 @@
+package jrc
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+//ErrCircuitOpen is returned without contacting the Server while its circuit breaker is open
+var ErrCircuitOpen = errors.New("jrc: circuit breaker is open")
+
+//BreakerPolicy controls when the circuit breaker opens and how it recovers
+//  while open every call fails with ErrCircuitOpen, after Cooldown a single probe call is let through
+//  and a successful probe closes the breaker again
+type BreakerPolicy struct {
+	ConsecutiveFailures int           //open after this many transport failures in a row, 0 to ignore
+	ErrorRate           float64       //open when this fraction of the last Window calls failed, 0 to ignore
+	Window              int           //calls the error rate is measured over, it is not checked until the window is full
+	Cooldown            time.Duration //how long to stay open before probing
+}
+
+type breakerState uint8
+
+const (
+	breakerClosed breakerState = iota
+	breakerOpen
+	breakerHalfOpen
+)
+
+//breaker is a circuit breaker following a BreakerPolicy
+type breaker struct {
+	p BreakerPolicy
+
+	mu          sync.Mutex
+	state       breakerState
+	openedAt    time.Time
+	probing     bool
+	consecutive int
+	outcomes    []bool //ring of the last Window outcomes, true for a failure
+	next        int
+	filled      int
+	failures    int
+}
+
+func newBreaker(p BreakerPolicy) *breaker {
+	return &breaker{p: p, outcomes: make([]bool, p.Window)}
+}
+
+//allow reports whether a call may go ahead, moving an open breaker to half-open once its cooldown has passed
+func (b *breaker) allow() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	switch b.state {
+	case breakerOpen:
+		if time.Since(b.openedAt) < b.p.Cooldown {
+			return ErrCircuitOpen
+		}
+		b.state = breakerHalfOpen
+		b.probing = true
+		return nil
+	case breakerHalfOpen:
+		if b.probing {
+			return ErrCircuitOpen
+		}
+		b.probing = true
+		return nil
+	default:
+		return nil
+	}
+}
+
+//record notes the outcome of a call that allow let through
+func (b *breaker) record(err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.state == breakerHalfOpen {
+		b.probing = false
+		if err != nil {
+			b.open()
+		} else {
+			b.reset()
+		}
+		return
+	}
+	failed := err != nil
+	if failed {
+		b.consecutive++
+	} else {
+		b.consecutive = 0
+	}
+	if len(b.outcomes) > 0 {
+		if b.filled == len(b.outcomes) && b.outcomes[b.next] {
+			b.failures--
+		}
+		b.outcomes[b.next] = failed
+		if failed {
+			b.failures++
+		}
+		b.next = (b.next + 1) % len(b.outcomes)
+		if b.filled < len(b.outcomes) {
+			b.filled++
+		}
+	}
+	if b.p.ConsecutiveFailures > 0 && b.consecutive >= b.p.ConsecutiveFailures {
+		b.open()
+		return
+	}
+	if b.p.ErrorRate > 0 && b.filled == len(b.outcomes) && b.filled > 0 &&
+		float64(b.failures)/float64(b.filled) >= b.p.ErrorRate {
+		b.open()
+	}
+}
+
+func (b *breaker) open() {
+	b.state = breakerOpen
+	b.openedAt = time.Now()
+}
+
+//reset closes the breaker and forgets past outcomes
+func (b *breaker) reset() {
+	b.state = breakerClosed
+	b.consecutive = 0
+	b.next = 0
+	b.filled = 0
+	b.failures = 0
+}
+
+func (srv *Server) setBreaker(p BreakerPolicy) error {
+	if p.ConsecutiveFailures < 0 || p.Window < 0 || p.Cooldown < 0 {
+		return errors.New("jrc: breaker values cannot be negative")
+	}
+	if p.ErrorRate < 0 || p.ErrorRate > 1 {
+		return errors.New("jrc: breaker error rate must be between 0 and 1")
+	}
+	if p.ErrorRate > 0 && p.Window == 0 {
+		return errors.New("jrc: breaker error rate needs a window")
+	}
+	srv.breaker = newBreaker(p)
+	return nil
+}
+
+//CircuitBreaker stops calls to the Server after repeated transport failures, so a dead endpoint fails fast
+func CircuitBreaker(p BreakerPolicy) func(server *Server) error {
+	return func(srv *Server) error {
+		return srv.setBreaker(p)
+	}
+}
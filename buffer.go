@@ -0,0 +1,52 @@
+package jrc
+
+import "sync"
+
+//maxPooledBuffer keeps the odd huge response from pinning its memory in the pool
+const maxPooledBuffer = 8 << 20
+
+//bufPool holds response body buffers for reuse between calls when PoolBuffers is on
+var bufPool sync.Pool
+
+//getBuffer returns an empty buffer with room for at least n bytes, from the pool when one large enough is there
+func getBuffer(n int) []byte {
+	if n < 0 {
+		n = 0
+	}
+	if p, ok := bufPool.Get().(*[]byte); ok {
+		if cap(*p) >= n {
+			return (*p)[:0]
+		}
+		bufPool.Put(p)
+	}
+	return make([]byte, 0, n)
+}
+
+//putBuffer hands b back to the pool, b must not be used afterwards
+func putBuffer(b []byte) {
+	if cap(b) == 0 || cap(b) > maxPooledBuffer {
+		return
+	}
+	bufPool.Put(&b)
+}
+
+//PoolBuffers reuses response body buffers between calls to cut allocations, the bodies are recycled once parsed
+//  bodies returned by ExecBatchFast are the caller's until passed to Release, so they can be kept as they are
+//  with a custom Transport, it must not hold on to the bodies it returns while this is on
+func PoolBuffers(b bool) func(server *Server) error {
+	return func(srv *Server) error {
+		srv.pool = b
+		return nil
+	}
+}
+
+//Release returns bodies from ExecBatchFast to the buffer pool when PoolBuffers is on, they must not be used afterwards
+//  there is no need to call it otherwise, bodies that are never released are left to the garbage collector
+func (srv *Server) Release(bodies [][]byte) {
+	if !srv.pool {
+		return
+	}
+	for _, b := range bodies {
+		putBuffer(b)
+	}
+}
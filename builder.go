@@ -0,0 +1,108 @@
+package jrc
+
+import (
+	"context"
+	"errors"
+)
+
+//Batch builds up a batch of requests to run together, see Server.Batch
+type Batch struct {
+	srv  *Server
+	rs   RPCRequests
+	opts []CallOption
+	err  error
+}
+
+//Batch starts a batch whose requests are added with Add, and sent with Run
+//  every request is given an id from the Server's IDGenerator, unique within the batch
+func (srv *Server) Batch() *Batch {
+	return &Batch{srv: srv}
+}
+
+//Add appends a call to method with params built by Params
+//  an error building the params is returned by Run
+func (b *Batch) Add(method string, params ...interface{}) *Batch {
+	r, err := NewRequest(method, params...)
+	if err != nil {
+		if b.err == nil {
+			b.err = err
+		}
+		return b
+	}
+	b.rs = append(b.rs, r)
+	return b
+}
+
+//AddRequest appends a request built elsewhere, keeping its id if it has one
+func (b *Batch) AddRequest(r *RpcRequest) *Batch {
+	b.rs = append(b.rs, r)
+	return b
+}
+
+//With applies opts to every HTTP call made by Run
+func (b *Batch) With(opts ...CallOption) *Batch {
+	b.opts = append(b.opts, opts...)
+	return b
+}
+
+//Requests returns the requests added so far
+func (b *Batch) Requests() RPCRequests {
+	return b.rs
+}
+
+//Run executes the batch, returning the responses by position or by id
+//  with a *BatchError, or ctx.Err() if the context is done mid-batch, the results are returned too,
+//  holding no response for the requests of the calls that failed or were not made
+func (b *Batch) Run(ctx context.Context) (*BatchResults, error) {
+	if b.err != nil {
+		return nil, b.err
+	}
+	b.srv.fillIDs(b.rs)
+	resps, err := b.srv.ExecBatchContext(ctx, b.rs, b.opts...)
+	if err != nil && !partial(ctx, err) {
+		return nil, err
+	}
+	return &BatchResults{rs: b.rs, resps: Correlate(b.rs, resps), codec: b.srv.codec}, err
+}
+
+//BatchResults holds the responses to a Batch
+type BatchResults struct {
+	rs    RPCRequests
+	resps []*RpcResponse
+	codec Codec
+}
+
+//Len returns the number of requests in the batch
+func (br *BatchResults) Len() int {
+	return len(br.rs)
+}
+
+//At returns the response to the i-th request added, nil if the Server sent none
+func (br *BatchResults) At(i int) *RpcResponse {
+	return br.resps[i]
+}
+
+//ByID returns the response to the request with the given id, nil if there is no such request or response
+func (br *BatchResults) ByID(id ID) *RpcResponse {
+	for i, r := range br.rs {
+		if r.ID() == id {
+			return br.resps[i]
+		}
+	}
+	return nil
+}
+
+//Into decodes the Result of the i-th request into v, returning the RpcError as the error if the call failed
+func (br *BatchResults) Into(i int, v interface{}) error {
+	resp := br.resps[i]
+	if resp == nil {
+		return errors.New("jrc: no response for request " + br.rs[i].ID().String())
+	}
+	if resp.Error != nil {
+		return resp.Error
+	}
+	if len(resp.Result) > 0 {
+		return br.codec.Unmarshal(resp.Result, v)
+	}
+	return nil
+}
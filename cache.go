@@ -0,0 +1,162 @@
+package jrc
+
+import (
+	"container/list"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/cfoxon/jrc/internal/json"
+)
+
+//Cache stores the Results of calls under a key made from their method and params, see UseCache
+//  Get and Set are called from every goroutine making calls and must be safe for concurrent use
+type Cache interface {
+	Get(key string) (json.RawMessage, bool)
+	Set(key string, result json.RawMessage, ttl time.Duration)
+}
+
+//lruCache is an in-memory Cache holding up to size results, evicting the least recently used
+type lruCache struct {
+	mu    sync.Mutex
+	size  int
+	order *list.List //most recently used first
+	items map[string]*list.Element
+}
+
+type lruEntry struct {
+	key     string
+	result  json.RawMessage
+	expires time.Time
+}
+
+//NewLRUCache returns an in-memory Cache holding up to size results, dropping the least recently used beyond that
+func NewLRUCache(size int) Cache {
+	if size < 1 {
+		size = 1
+	}
+	return &lruCache{size: size, order: list.New(), items: make(map[string]*list.Element)}
+}
+
+//Get implements Cache, expired results are dropped rather than returned
+func (c *lruCache) Get(key string) (json.RawMessage, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	el, ok := c.items[key]
+	if !ok {
+		return nil, false
+	}
+	e := el.Value.(*lruEntry)
+	if time.Now().After(e.expires) {
+		c.order.Remove(el)
+		delete(c.items, key)
+		return nil, false
+	}
+	c.order.MoveToFront(el)
+	return e.result, true
+}
+
+//Set implements Cache
+func (c *lruCache) Set(key string, result json.RawMessage, ttl time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	expires := time.Now().Add(ttl)
+	if el, ok := c.items[key]; ok {
+		e := el.Value.(*lruEntry)
+		e.result, e.expires = result, expires
+		c.order.MoveToFront(el)
+		return
+	}
+	c.items[key] = c.order.PushFront(&lruEntry{key: key, result: result, expires: expires})
+	for c.order.Len() > c.size {
+		el := c.order.Back()
+		c.order.Remove(el)
+		delete(c.items, el.Value.(*lruEntry).key)
+	}
+}
+
+//resultCache is the Server's Cache along with what it applies to
+type resultCache struct {
+	c       Cache
+	ttl     time.Duration
+	methods map[string]bool
+}
+
+func (srv *Server) setCache(c Cache, ttl time.Duration, methods []string) error {
+	if c == nil {
+		srv.cache = nil
+		return nil
+	}
+	if ttl <= 0 {
+		return errors.New("jrc: cache ttl must be positive")
+	}
+	if len(methods) == 0 {
+		return errors.New("jrc: UseCache needs the methods whose results can be cached")
+	}
+	rc := &resultCache{c: c, ttl: ttl, methods: make(map[string]bool, len(methods))}
+	for _, m := range methods {
+		rc.methods[m] = true
+	}
+	srv.cache = rc
+	return nil
+}
+
+//UseCache keeps successful Results of the given methods in c for ttl, so identical calls are answered without the network
+//  only list idempotent read methods; calls match on method and params, and the cached Result is returned under the new call's id
+//  it applies to Exec, ExecBatch and the calls built on them, ExecBatchFast and ExecBatchStream always reach the Server
+//  nil c turns caching off
+func UseCache(c Cache, ttl time.Duration, methods ...string) func(server *Server) error {
+	return func(srv *Server) error {
+		return srv.setCache(c, ttl, methods)
+	}
+}
+
+//key returns the cache key for r, or false if r is not to be cached
+func (rc *resultCache) key(r *RpcRequest) (string, bool) {
+	if !rc.methods[r.Method] {
+		return "", false
+	}
+	return callKey(r)
+}
+
+//callKey identifies a call by its method and params, false if the params can't be marshalled
+func callKey(r *RpcRequest) (string, bool) {
+	params, err := json.Marshal(r.Params)
+	if err != nil {
+		return "", false
+	}
+	return r.Method + "\x00" + string(params), true
+}
+
+//lookup answers the requests of rs found in the cache, returning the rest to be sent along with their keys
+func (rc *resultCache) lookup(rs RPCRequests) (hits []RpcResponse, misses RPCRequests, keys map[*RpcRequest]string) {
+	keys = make(map[*RpcRequest]string)
+	for _, r := range rs {
+		key, ok := rc.key(r)
+		if !ok {
+			misses = append(misses, r)
+			continue
+		}
+		if result, ok := rc.c.Get(key); ok {
+			//a copy, so callers can't change what the cache holds
+			hits = append(hits, RpcResponse{JSONRPC: "2.0", Result: append(json.RawMessage(nil), result...), ID: r.ID()})
+			continue
+		}
+		misses = append(misses, r)
+		keys[r] = key
+	}
+	return hits, misses, keys
+}
+
+//store caches the successful responses to the requests of rs that have keys
+func (rc *resultCache) store(rs RPCRequests, resps []RpcResponse, keys map[*RpcRequest]string) {
+	if len(keys) == 0 {
+		return
+	}
+	for i, resp := range Correlate(rs, resps) {
+		if key, ok := keys[rs[i]]; ok && resp != nil && resp.Error == nil && resp.Result != nil {
+			//the caller owns resp.Result and may change it
+			rc.c.Set(key, append(json.RawMessage(nil), resp.Result...), rc.ttl)
+		}
+	}
+}
@@ -0,0 +1,16 @@
+package jrc
+
+import (
+	"context"
+
+	"github.com/cfoxon/jrc/internal/json"
+)
+
+//Caller is the part of a Server that executes requests, for code that should also accept a fake in its tests
+type Caller interface {
+	Exec(r RpcRequest, opts ...CallOption) (*RpcResponse, error)
+	ExecBatch(rs RPCRequests, opts ...CallOption) ([]RpcResponse, error)
+	Call(ctx context.Context, method string, params ...interface{}) (json.RawMessage, error)
+}
+
+var _ Caller = (*Server)(nil)
@@ -0,0 +1,69 @@
+package jrc
+
+//CallOption changes how a single call is made without changing the Server's configuration
+type CallOption func(*callConfig)
+
+//callConfig holds the settings for a single call, built from its CallOptions
+type callConfig struct {
+	headers  []header
+	notify   bool       //no response is expected for the call
+	sizes    []int      //the number of requests in each body, when known
+	profiles []*profile //the profile of each body, nil when the Server has none
+	gets     []bool     //whether each body is sent as a GET, nil when the Server has no ReadOverGET methods
+	conn     int        //MaxCon for this call, zero for the Server's
+	batch    int        //MaxBatch for this call, zero for the Server's
+	path     string     //from WithPath
+	progress func(Progress)
+	stats    *BatchStats
+}
+
+//batchSize is the number of requests to put in each HTTP call
+func (cfg *callConfig) batchSize(srv *Server) int {
+	if cfg.batch > 0 {
+		return cfg.batch
+	}
+	return srv.batchSize()
+}
+
+func newCallConfig(opts []CallOption) *callConfig {
+	cfg := &callConfig{}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	return cfg
+}
+
+//WithHeader adds a header to the HTTP requests made for one call, taking precedence over headers set on the Server
+func WithHeader(key, value string) CallOption {
+	return func(cfg *callConfig) {
+		cfg.headers = append(cfg.headers, header{key: key, value: value})
+	}
+}
+
+//WithMaxCon limits one batch to n HTTP calls in flight at once in place of MaxCon
+//  above MaxCon, extra workers are started for as long as the batch runs
+func WithMaxCon(n int) CallOption {
+	return func(cfg *callConfig) {
+		if n > 0 {
+			cfg.conn = n
+		}
+	}
+}
+
+//WithPath sends one call to path p on the host of each endpoint in place of the endpoint's own path,
+//  for daemons serving several RPC namespaces such as /jsonrpc and /wallet; a query in p replaces the endpoint's
+//  and without one the endpoint's is kept; calls over WebSockets and other connections without paths ignore it
+func WithPath(p string) CallOption {
+	return func(cfg *callConfig) {
+		cfg.path = p
+	}
+}
+
+//WithMaxBatch splits one batch into HTTP calls of at most n requests in place of MaxBatch or AdaptiveBatch
+func WithMaxBatch(n int) CallOption {
+	return func(cfg *callConfig) {
+		if n > 0 {
+			cfg.batch = n
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package jrc_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/cfoxon/jrc"
+	"github.com/cfoxon/jrc/jrctest"
+)
+
+func TestCancelledBatchStopsDispatching(t *testing.T) {
+	ts := jrctest.NewServer()
+	defer ts.Close()
+	ts.Echo("fast")
+	ts.Echo("slow")
+	ts.Delay("slow", 300*time.Millisecond)
+	srv, _ := ts.Client(jrc.MaxCon(1), jrc.MaxBatch(1), jrc.QueueSize(10))
+	defer srv.Close()
+
+	rs := jrc.RPCRequests{{JsonRpc: "2.0", Id: 1, Method: "fast"}, {JsonRpc: "2.0", Id: 2, Method: "fast"}}
+	for i := 3; i <= 20; i++ {
+		rs = append(rs, &jrc.RpcRequest{JsonRpc: "2.0", Id: i, Method: "slow"})
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 150*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	resps, err := srv.ExecBatchContext(ctx, rs)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("got %v, want context.DeadlineExceeded", err)
+	}
+	if time.Since(start) > 250*time.Millisecond {
+		t.Errorf("returned after %v, not when the context expired", time.Since(start))
+	}
+	//the responses that arrived before the deadline are kept
+	if len(resps) != 2 {
+		t.Errorf("got %d responses, want the 2 fast ones", len(resps))
+	}
+
+	//the calls queued behind the slow one are dropped, not made after the caller gave up
+	time.Sleep(400 * time.Millisecond)
+	if n := ts.Posts(); n != 3 {
+		t.Errorf("%d calls made, want 3", n)
+	}
+}
+
+func TestDoneContextMakesNoCalls(t *testing.T) {
+	ts := jrctest.NewServer()
+	defer ts.Close()
+	ts.Echo("echo")
+	srv, _ := ts.Client()
+	defer srv.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := srv.ExecBatchContext(ctx, twoRequests()); !errors.Is(err, context.Canceled) {
+		t.Fatalf("got %v, want context.Canceled", err)
+	}
+	if n := ts.Posts(); n != 0 {
+		t.Errorf("%d calls made with a cancelled context", n)
+	}
+}
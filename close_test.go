@@ -0,0 +1,61 @@
+package jrc_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/cfoxon/jrc"
+	"github.com/cfoxon/jrc/jrctest"
+)
+
+func TestCloseWaitsForCallsInFlight(t *testing.T) {
+	ts := jrctest.NewServer()
+	defer ts.Close()
+	ts.Echo("echo")
+	ts.Delay("echo", 100*time.Millisecond)
+	srv, _ := ts.Client()
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := srv.ExecBatch(twoRequests())
+		done <- err
+	}()
+	time.Sleep(20 * time.Millisecond)
+	if err := srv.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := <-done; err != nil {
+		t.Errorf("call in flight during Close failed: %v", err)
+	}
+	if _, err := srv.ExecBatch(twoRequests()); !errors.Is(err, jrc.ErrClosed) {
+		t.Errorf("got %v after Close, want ErrClosed", err)
+	}
+	if err := srv.Close(); err != nil {
+		t.Errorf("closing again: %v", err)
+	}
+}
+
+func TestCloseDropsQueuedCalls(t *testing.T) {
+	ts := jrctest.NewServer()
+	defer ts.Close()
+	ts.Echo("echo")
+	ts.Delay("echo", 100*time.Millisecond)
+	srv, _ := ts.Client(jrc.MaxCon(1), jrc.MaxBatch(1))
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := srv.ExecBatch(append(twoRequests(), twoRequests()...))
+		done <- err
+	}()
+	time.Sleep(20 * time.Millisecond)
+	srv.Close()
+	//the call being made finishes, the three behind it are never made
+	var be *jrc.BatchError
+	if err := <-done; !errors.As(err, &be) || len(be.Failed) != 3 || !errors.Is(be.Failed[0].Err, jrc.ErrClosed) {
+		t.Fatalf("got %v, want the 3 queued calls failed with ErrClosed", err)
+	}
+	if n := ts.Posts(); n != 1 {
+		t.Errorf("%d calls made, want 1", n)
+	}
+}
@@ -0,0 +1,206 @@
+//Command jrc makes JSON RPC calls from the command line, for scripting and for poking at nodes
+//
+//  jrc call -url https://api.hive.blog condenser_api.get_dynamic_global_properties
+//  jrc call -url http://localhost:8332 -H "Authorization: Basic ..." getblockhash 1000
+//  jrc batch -url https://api.hive.blog -max-con 8 < requests.ndjson
+//
+//call sends one request with the params given as a single JSON array or object, or as one argument per param,
+//  each read as JSON if it is valid JSON and as a string otherwise, and prints the result
+//batch reads one request object per line from stdin, such as {"method":"get_block","params":[1]}, numbering
+//  those without an id, and prints one response per line in the order of the requests
+//the exit status is 1 when a call gets an error response and 2 when it could not be made
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/cfoxon/jrc"
+)
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+//headers collects repeated -H flags
+type headers []string
+
+func (h *headers) String() string {
+	return strings.Join(*h, ", ")
+}
+
+func (h *headers) Set(v string) error {
+	if !strings.Contains(v, ":") {
+		return errors.New("header must be Name: value")
+	}
+	*h = append(*h, v)
+	return nil
+}
+
+//config is what both commands take from their flags
+type config struct {
+	url      string
+	headers  headers
+	timeout  time.Duration
+	maxCon   int
+	maxBatch int
+	raw      bool
+	dump     bool
+}
+
+func usage(stderr io.Writer) int {
+	fmt.Fprintln(stderr, "usage: jrc call -url URL [flags] method [params...]")
+	fmt.Fprintln(stderr, "       jrc batch -url URL [flags] < requests.ndjson")
+	return 2
+}
+
+//run runs the command in args, returning the exit status
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	if len(args) < 1 || (args[0] != "call" && args[0] != "batch") {
+		return usage(stderr)
+	}
+	var cfg config
+	fs := flag.NewFlagSet("jrc "+args[0], flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.StringVar(&cfg.url, "url", "", "address of the server, http(s)://, ws(s)://, tcp:// or ipc://")
+	fs.Var(&cfg.headers, "H", "header to send, as Name: value, repeatable")
+	fs.DurationVar(&cfg.timeout, "timeout", 30*time.Second, "limit on the whole command, 0 for none")
+	fs.IntVar(&cfg.maxCon, "max-con", 4, "calls in flight at once")
+	fs.IntVar(&cfg.maxBatch, "max-batch", 50, "requests per call in batch mode, 0 for all in one")
+	fs.BoolVar(&cfg.raw, "raw", false, "print without indenting, results exactly as the server sent them")
+	fs.BoolVar(&cfg.dump, "v", false, "dump the HTTP exchanges to stderr")
+	if err := fs.Parse(args[1:]); err != nil {
+		return 2
+	}
+	if cfg.url == "" {
+		fmt.Fprintln(stderr, "jrc: -url is required")
+		return usage(stderr)
+	}
+	srv, err := cfg.server(stderr)
+	if err != nil {
+		fmt.Fprintln(stderr, "jrc:", err)
+		return 2
+	}
+	defer srv.Close()
+	ctx := context.Background()
+	if cfg.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, cfg.timeout)
+		defer cancel()
+	}
+	if args[0] == "call" {
+		return call(ctx, srv, cfg, fs.Args(), stdout, stderr)
+	}
+	return batch(ctx, srv, cfg, stdin, stdout, stderr)
+}
+
+func (cfg config) server(stderr io.Writer) (*jrc.Server, error) {
+	opts := []func(*jrc.Server) error{jrc.MaxCon(cfg.maxCon), jrc.MaxBatch(cfg.maxBatch), jrc.AutoID(true), jrc.PreserveOrder(true)}
+	for _, h := range cfg.headers {
+		k, v, _ := strings.Cut(h, ":")
+		opts = append(opts, jrc.Header(strings.TrimSpace(k), strings.TrimSpace(v)))
+	}
+	if cfg.dump {
+		opts = append(opts, jrc.Dump(stderr))
+	}
+	return jrc.NewServer(cfg.url, opts...)
+}
+
+func call(ctx context.Context, srv *jrc.Server, cfg config, args []string, stdout, stderr io.Writer) int {
+	if len(args) < 1 {
+		return usage(stderr)
+	}
+	r := jrc.RpcRequest{JsonRpc: "2.0", Id: 1, Method: args[0]}
+	if ps := args[1:]; len(ps) == 1 && (strings.HasPrefix(ps[0], "[") || strings.HasPrefix(ps[0], "{")) {
+		if !json.Valid([]byte(ps[0])) {
+			fmt.Fprintln(stderr, "jrc: params are not valid JSON")
+			return 2
+		}
+		r.Params = json.RawMessage(ps[0])
+	} else if len(ps) > 0 {
+		params := make([]json.RawMessage, len(ps))
+		for i, p := range ps {
+			if json.Valid([]byte(p)) {
+				params[i] = json.RawMessage(p)
+			} else {
+				params[i], _ = json.Marshal(p)
+			}
+		}
+		r.Params = params
+	}
+	resp, err := srv.ExecContext(ctx, r)
+	if err != nil {
+		fmt.Fprintln(stderr, "jrc:", err)
+		return 2
+	}
+	if resp.Error != nil {
+		fmt.Fprintln(stderr, "jrc:", resp.Error)
+		return 1
+	}
+	write(stdout, resp.Result, cfg.raw)
+	return 0
+}
+
+func batch(ctx context.Context, srv *jrc.Server, cfg config, stdin io.Reader, stdout, stderr io.Writer) int {
+	var rs jrc.RPCRequests
+	sc := bufio.NewScanner(stdin)
+	sc.Buffer(nil, 64<<20)
+	for line := 1; sc.Scan(); line++ {
+		b := bytes.TrimSpace(sc.Bytes())
+		if len(b) == 0 {
+			continue
+		}
+		r := &jrc.RpcRequest{}
+		if err := json.Unmarshal(b, r); err != nil || r.Method == "" {
+			fmt.Fprintf(stderr, "jrc: line %d is not a request: %s\n", line, b)
+			return 2
+		}
+		if r.JsonRpc == "" {
+			r.JsonRpc = "2.0"
+		}
+		rs = append(rs, r)
+	}
+	if err := sc.Err(); err != nil {
+		fmt.Fprintln(stderr, "jrc:", err)
+		return 2
+	}
+	resps, err := srv.ExecBatchContext(ctx, rs)
+	status := 0
+	for _, resp := range resps {
+		b, merr := json.Marshal(resp)
+		if merr != nil {
+			fmt.Fprintln(stderr, "jrc:", merr)
+			return 2
+		}
+		write(stdout, b, cfg.raw)
+		if resp.Error != nil {
+			status = 1
+		}
+	}
+	if err != nil {
+		//the responses that did arrive have been printed
+		fmt.Fprintln(stderr, "jrc:", err)
+		return 2
+	}
+	return status
+}
+
+//write prints b on a line of its own, indented unless raw is set
+func write(w io.Writer, b []byte, raw bool) {
+	if !raw {
+		var buf bytes.Buffer
+		if json.Indent(&buf, b, "", "  ") == nil {
+			b = buf.Bytes()
+		}
+	}
+	w.Write(append(b, '\n'))
+}
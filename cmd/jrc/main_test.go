@@ -0,0 +1,87 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/cfoxon/jrc"
+	"github.com/cfoxon/jrc/internal/json"
+	"github.com/cfoxon/jrc/jrctest"
+)
+
+func testServer(t *testing.T) *jrctest.Server {
+	ts := jrctest.NewServer()
+	t.Cleanup(ts.Close)
+	ts.Echo("echo")
+	ts.Error("fail", jrc.CodeInvalidParams, "bad params")
+	ts.Handle("sum", func(_ context.Context, params json.RawMessage) (interface{}, error) {
+		var ns []int
+		json.Unmarshal(params, &ns)
+		total := 0
+		for _, n := range ns {
+			total += n
+		}
+		return total, nil
+	})
+	return ts
+}
+
+func runCmd(stdin string, args ...string) (int, string, string) {
+	var stdout, stderr bytes.Buffer
+	status := run(args, strings.NewReader(stdin), &stdout, &stderr)
+	return status, stdout.String(), stderr.String()
+}
+
+func TestCall(t *testing.T) {
+	ts := testServer(t)
+	for _, c := range []struct {
+		args   []string
+		status int
+		out    string
+	}{
+		{[]string{"call", "-url", ts.URL, "-raw", "echo", `[1,{"a":2}]`}, 0, `[1,{"a":2}]` + "\n"},
+		{[]string{"call", "-url", ts.URL, "-raw", "echo", "7", "abc", "true"}, 0, `[7,"abc",true]` + "\n"},
+		{[]string{"call", "-url", ts.URL, "echo", `{"a":1}`}, 0, "{\n  \"a\": 1\n}\n"},
+		{[]string{"call", "-url", ts.URL, "fail"}, 1, ""},
+		{[]string{"call", "-url", "http://127.0.0.1:1", "echo"}, 2, ""},
+		{[]string{"call", "echo"}, 2, ""},
+		{[]string{"frobnicate"}, 2, ""},
+	} {
+		status, out, stderr := runCmd("", c.args...)
+		if status != c.status || out != c.out {
+			t.Errorf("%v: status %d, output %q, want %d and %q; stderr %s", c.args, status, out, c.status, c.out, stderr)
+		}
+	}
+}
+
+func TestBatch(t *testing.T) {
+	ts := testServer(t)
+	in := `{"method":"sum","params":[1,2]}
+
+{"jsonrpc":"2.0","id":"x","method":"echo","params":["y"]}
+{"method":"fail"}
+{"method":"sum","params":[3]}
+`
+	status, out, stderr := runCmd(in, "batch", "-url", ts.URL, "-raw", "-max-batch", "2")
+	if status != 1 {
+		t.Errorf("status %d with an error response, want 1; stderr %s", status, stderr)
+	}
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	want := []string{`"result":3,"id":1`, `"result":["y"],"id":"x"`, `"code":-32602`, `"result":3,"id":3`}
+	if len(lines) != len(want) {
+		t.Fatalf("got %d responses, want %d:\n%s", len(lines), len(want), out)
+	}
+	for i, w := range want {
+		if !strings.Contains(lines[i], w) {
+			t.Errorf("response %d is %s, want it to hold %s", i, lines[i], w)
+		}
+	}
+	if posts := ts.Posts(); posts != 2 {
+		t.Errorf("%d calls, want 2 of -max-batch 2", posts)
+	}
+	if status, _, _ := runCmd("not json\n", "batch", "-url", ts.URL); status != 2 {
+		t.Errorf("status %d for a line that is not a request, want 2", status)
+	}
+}
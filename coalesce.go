@@ -0,0 +1,93 @@
+package jrc
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/cfoxon/jrc/internal/json"
+)
+
+//flightGroup shares one call among the concurrent identical calls made while it is in flight
+type flightGroup struct {
+	methods map[string]bool
+
+	mu    sync.Mutex
+	calls map[string]*flight
+}
+
+//flight is a call in progress, its outcome set once done is closed
+type flight struct {
+	done chan struct{}
+	resp *RpcResponse
+	err  error
+}
+
+func (srv *Server) setCoalesce(methods []string) error {
+	if len(methods) == 0 {
+		srv.flights = nil
+		return nil
+	}
+	g := &flightGroup{methods: make(map[string]bool, len(methods)), calls: make(map[string]*flight)}
+	for _, m := range methods {
+		if m == "" {
+			return errors.New("jrc: Coalesce needs method names")
+		}
+		g.methods[m] = true
+	}
+	srv.flights = g
+	return nil
+}
+
+//Coalesce makes one network call for concurrent single calls of the given methods with the same params, all sharing its response
+//  each caller gets the response under its own request's id; the first caller's CallOptions and context are the ones used,
+//  so a call cancelled by the first caller fails for all that joined it; only list idempotent read methods, none to turn it off
+//  it applies to Exec and the calls built on it, such as ExecInto, ExecTyped and Call, but not to batches
+func Coalesce(methods ...string) func(server *Server) error {
+	return func(srv *Server) error {
+		return srv.setCoalesce(methods)
+	}
+}
+
+//do makes the call r through exec, unless an identical one is in flight in which case it waits for that to finish
+func (g *flightGroup) do(ctx context.Context, r RpcRequest, exec func() (*RpcResponse, error)) (*RpcResponse, error) {
+	key, ok := "", g.methods[r.Method]
+	if ok {
+		key, ok = callKey(&r)
+	}
+	if !ok {
+		return exec()
+	}
+	g.mu.Lock()
+	if f, ok := g.calls[key]; ok {
+		g.mu.Unlock()
+		select {
+		case <-f.done:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		return f.share(r.ID())
+	}
+	f := &flight{done: make(chan struct{})}
+	g.calls[key] = f
+	g.mu.Unlock()
+
+	f.resp, f.err = exec()
+	g.mu.Lock()
+	delete(g.calls, key)
+	g.mu.Unlock()
+	close(f.done)
+	return f.share(r.ID())
+}
+
+//share returns the outcome of f as the response to a call with the given id
+//  each caller gets a copy, so they can use their response as their own
+func (f *flight) share(id ID) (*RpcResponse, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	resp := *f.resp
+	resp.Result = append(json.RawMessage(nil), f.resp.Result...)
+	resp.ID = id
+	return &resp, nil
+}
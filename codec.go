@@ -0,0 +1,84 @@
+package jrc
+
+import (
+	stdjson "encoding/json"
+	"errors"
+
+	"github.com/cfoxon/jrc/internal/json"
+)
+
+//jsonContentType is the Content-Type of requests encoded with JSONCodec
+const jsonContentType = "application/json"
+
+//Codec encodes the requests sent to the Server and decodes its responses, JSONCodec unless UseCodec sets another
+//  RpcRequest, RpcResponse and ID must go through it as they do through encoding/json, json.RawMessage values
+//  being kept as they are encoded; Result then holds the codec's encoding, which ExecInto, ExecTyped, CallTyped
+//  and BatchResults decode with the same codec
+type Codec interface {
+	Marshal(v interface{}) ([]byte, error)
+	Unmarshal(data []byte, v interface{}) error
+	ContentType() string //sent as the Content-Type of HTTP requests
+}
+
+//JSONCodec is the default Codec, encoding JSON with goccy/go-json, or with encoding/json in builds with the jrc_stdjson tag
+type JSONCodec struct{}
+
+//Marshal implements Codec
+func (JSONCodec) Marshal(v interface{}) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+//Unmarshal implements Codec
+func (JSONCodec) Unmarshal(data []byte, v interface{}) error {
+	return json.Unmarshal(data, v)
+}
+
+//ContentType implements Codec
+func (JSONCodec) ContentType() string {
+	return jsonContentType
+}
+
+//StdJSONCodec encodes JSON with encoding/json, for payloads goccy/go-json gets wrong
+//  only requests and responses go through it, the jrc_stdjson build tag also covers the rest of jrc and drops goccy/go-json
+type StdJSONCodec struct{}
+
+//Marshal implements Codec
+func (StdJSONCodec) Marshal(v interface{}) ([]byte, error) {
+	return stdjson.Marshal(v)
+}
+
+//Unmarshal implements Codec
+func (StdJSONCodec) Unmarshal(data []byte, v interface{}) error {
+	return stdjson.Unmarshal(data, v)
+}
+
+//ContentType implements Codec
+func (StdJSONCodec) ContentType() string {
+	return jsonContentType
+}
+
+//isJSON reports whether c encodes JSON, which jrc can also read itself
+func isJSON(c Codec) bool {
+	switch c.(type) {
+	case JSONCodec, StdJSONCodec:
+		return true
+	}
+	return false
+}
+
+func (srv *Server) setCodec(c Codec) error {
+	if c == nil {
+		return errors.New("jrc: Codec cannot be nil")
+	}
+	srv.codec = c
+	return nil
+}
+
+//UseCodec encodes requests and decodes responses with c, such as CBOR or msgpack, in place of JSON
+//  it applies to HTTP servers; tcp://, ipc://, ws:// and command servers match responses to calls as JSON so
+//  need a codec whose output is JSON, and ExecBatchFast returns the bodies as c encoded them
+func UseCodec(c Codec) func(server *Server) error {
+	return func(srv *Server) error {
+		return srv.setCodec(c)
+	}
+}
@@ -0,0 +1,126 @@
+package jrc_test
+
+import (
+	"context"
+	stdjson "encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/cfoxon/jrc"
+	"github.com/cfoxon/jrc/jrctest"
+)
+
+//stdCodec encodes with encoding/json under another content type, counting what it decodes
+type stdCodec struct {
+	decoded *int32
+}
+
+func (c stdCodec) Marshal(v interface{}) ([]byte, error) { return stdjson.Marshal(v) }
+
+func (c stdCodec) Unmarshal(b []byte, v interface{}) error {
+	atomic.AddInt32(c.decoded, 1)
+	return stdjson.Unmarshal(b, v)
+}
+
+func (stdCodec) ContentType() string { return "application/json-rpc" }
+
+func TestUseCodec(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Content-Type") != "application/json-rpc" {
+			http.Error(w, "wrong content type "+r.Header.Get("Content-Type"), http.StatusUnsupportedMediaType)
+			return
+		}
+		var rs []struct{ Id stdjson.RawMessage }
+		b, _ := io.ReadAll(r.Body)
+		if err := stdjson.Unmarshal(b, &rs); err != nil {
+			//a single request gets a single response
+			w.Write([]byte(`{"jsonrpc":"2.0","id":1,"result":[7,8,9]}`))
+			return
+		}
+		out := "["
+		for i, q := range rs {
+			if i > 0 {
+				out += ","
+			}
+			out += `{"jsonrpc":"2.0","id":` + string(q.Id) + `,"result":[7,8,9]}`
+		}
+		w.Write([]byte(out + "]"))
+	}))
+	defer ts.Close()
+	var decoded int32
+	srv, err := jrc.NewServer(ts.URL, jrc.UseCodec(stdCodec{&decoded}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer srv.Close()
+	ctx := context.Background()
+
+	var got []int
+	if err := srv.ExecInto(jrc.RpcRequest{JsonRpc: "2.0", Id: 1, Method: "m"}, &got); err != nil || len(got) != 3 {
+		t.Fatalf("ExecInto: %v, %v", got, err)
+	}
+	if n, err := jrc.CallTyped[[]int](ctx, srv, "m"); err != nil || n[2] != 9 {
+		t.Fatalf("CallTyped: %v, %v", n, err)
+	}
+	sum := 0
+	if err := jrc.ExecEach(ctx, srv, jrc.RpcRequest{JsonRpc: "2.0", Id: 1, Method: "m"}, func(v int) error {
+		sum += v
+		return nil
+	}); err != nil || sum != 24 {
+		t.Fatalf("ExecEach: %d, %v", sum, err)
+	}
+	//ExecInto, CallTyped and ExecEach each decode a response body and a result
+	if decoded != 6 {
+		t.Errorf("codec decoded %d times, want 6", decoded)
+	}
+	//the single object answering a 1.0 request is decoded too
+	srv.SetOption(jrc.Version("1.0"))
+	got = nil
+	if err := srv.ExecInto(jrc.RpcRequest{Id: 1, Method: "m"}, &got); err != nil || len(got) != 3 {
+		t.Fatalf("ExecInto in 1.0 mode: %v, %v", got, err)
+	}
+	if _, err := jrc.NewServer(ts.URL, jrc.UseCodec(nil)); err == nil {
+		t.Error("nil Codec accepted")
+	}
+}
+
+func TestStdJSONCodec(t *testing.T) {
+	ts := jrctest.NewServer()
+	defer ts.Close()
+	ts.Echo("echo")
+	srv, _ := ts.Client(jrc.UseCodec(jrc.StdJSONCodec{}))
+	defer srv.Close()
+	resps, err := srv.ExecBatch(twoRequests())
+	if err != nil || len(resps) != 2 || string(resps[1].Result) != "[2]" {
+		t.Fatal(resps, err)
+	}
+}
+
+func TestContentTypeAndAcceptEncoding(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if ct := r.Header.Get("Content-Type"); ct != "application/json-rpc; charset=utf-8" {
+			http.Error(w, "wrong content type "+ct, http.StatusUnsupportedMediaType)
+			return
+		}
+		if ae := r.Header.Get("Accept-Encoding"); ae != "gzip" {
+			http.Error(w, "wrong accept encoding "+ae, http.StatusNotAcceptable)
+			return
+		}
+		w.Write([]byte(`[{"jsonrpc":"2.0","id":1,"result":true}]`))
+	}))
+	defer ts.Close()
+	srv, err := jrc.NewServer(ts.URL, jrc.ContentType("application/json-rpc; charset=utf-8"), jrc.AcceptEncoding("gzip"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer srv.Close()
+	if res, err := srv.Call(context.Background(), "ok"); err != nil || string(res) != "true" {
+		t.Fatalf("got %s, %v", res, err)
+	}
+	if _, err := jrc.NewServer(ts.URL, jrc.AcceptEncoding("deflate")); err == nil {
+		t.Error("an encoding the client cannot decode was accepted")
+	}
+}
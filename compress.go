@@ -0,0 +1,111 @@
+package jrc
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/gzip"
+	"github.com/klauspost/compress/zstd"
+	"github.com/valyala/fasthttp"
+)
+
+func (srv *Server) setCompressRequests(minSize int) error {
+	if minSize < 0 {
+		return errors.New("jrc: compression threshold cannot be negative")
+	}
+	srv.gzipMin = minSize
+	return nil
+}
+
+//CompressRequests gzips request bodies of at least minSize bytes and sends them with Content-Encoding: gzip
+//  the Server must accept compressed requests, 0 turns compression off
+//  it only applies to HTTP, as tcp://, ipc://, ws:// and command servers have no headers to announce it
+func CompressRequests(minSize int) func(server *Server) error {
+	return func(srv *Server) error {
+		return srv.setCompressRequests(minSize)
+	}
+}
+
+//compress gzips m's body in place if it is over the Server's threshold
+func (srv *Server) compress(m *Message) {
+	if srv.gzipMin == 0 || len(m.Body) < srv.gzipMin {
+		return
+	}
+	if srv.tr == nil {
+		if streamOf(srv.endpoints[0].tr) != nil {
+			return
+		}
+	}
+	m.Body = fasthttp.AppendGzipBytes(nil, m.Body)
+	if m.Header == nil {
+		m.Header = http.Header{}
+	}
+	m.Header.Set("Content-Encoding", "gzip")
+}
+
+//acceptEncoding lists the response encodings decodeBody understands
+const acceptEncoding = "gzip, br, zstd"
+
+var (
+	zstdOnce sync.Once
+	zstdDec  *zstd.Decoder
+	zstdErr  error
+)
+
+//decodeBody undoes the Content-Encoding of a response body, bodies in other encodings are returned as they are
+func decodeBody(encoding string, b []byte, limit int) ([]byte, error) {
+	enc := strings.ToLower(strings.TrimSpace(encoding))
+	if limit > 0 && enc != "" && enc != "identity" {
+		return decodeLimited(enc, b, limit)
+	}
+	switch enc {
+	case "gzip":
+		return fasthttp.AppendGunzipBytes(nil, b)
+	case "br":
+		return io.ReadAll(brotli.NewReader(bytes.NewReader(b)))
+	case "zstd":
+		//a single decoder is safe for concurrent DecodeAll calls
+		zstdOnce.Do(func() {
+			zstdDec, zstdErr = zstd.NewReader(nil)
+		})
+		if zstdErr != nil {
+			return nil, zstdErr
+		}
+		return zstdDec.DecodeAll(b, nil)
+	default:
+		if limit > 0 && len(b) > limit {
+			return nil, ErrResponseTooLarge
+		}
+		return b, nil
+	}
+}
+
+//decodeLimited decompresses b as a stream, stopping once the output passes limit
+func decodeLimited(enc string, b []byte, limit int) ([]byte, error) {
+	var r io.Reader
+	switch enc {
+	case "gzip":
+		zr, err := gzip.NewReader(bytes.NewReader(b))
+		if err != nil {
+			return nil, err
+		}
+		r = zr
+	case "br":
+		r = brotli.NewReader(bytes.NewReader(b))
+	case "zstd":
+		zr, err := zstd.NewReader(bytes.NewReader(b), zstd.WithDecoderConcurrency(1))
+		if err != nil {
+			return nil, err
+		}
+		defer zr.Close()
+		r = zr
+	default:
+		return decodeBody(enc, b, 0)
+	}
+	return readLimited(r, limit, nil)
+}
@@ -0,0 +1,161 @@
+package jrc
+
+import (
+	"errors"
+	"os"
+	"time"
+
+	"github.com/cfoxon/jrc/internal/json"
+)
+
+//Config describes a Server in a form that can be kept in a JSON or YAML file, for NewServerFromConfig
+//  fields left at their zero value keep NewServer's defaults
+type Config struct {
+	URL       string            `json:"url" yaml:"url"`
+	Endpoints []string          `json:"endpoints,omitempty" yaml:"endpoints,omitempty"` //fallback addresses, as for Endpoints
+	Balance   string            `json:"balance,omitempty" yaml:"balance,omitempty"`     //priority, round-robin or least-latency
+	Cooldown  Duration          `json:"cooldown,omitempty" yaml:"cooldown,omitempty"`   //as for FailoverCooldown
+	Headers   map[string]string `json:"headers,omitempty" yaml:"headers,omitempty"`
+	Auth      AuthConfig        `json:"auth,omitempty" yaml:"auth,omitempty"`
+
+	MaxCon    int  `json:"max_con,omitempty" yaml:"max_con,omitempty"`
+	MaxBatch  *int `json:"max_batch,omitempty" yaml:"max_batch,omitempty"` //a pointer, as 0 sends a whole batch in one call
+	QueueSize int  `json:"queue_size,omitempty" yaml:"queue_size,omitempty"`
+
+	DialTimeout  Duration `json:"dial_timeout,omitempty" yaml:"dial_timeout,omitempty"`
+	ReadTimeout  Duration `json:"read_timeout,omitempty" yaml:"read_timeout,omitempty"`
+	WriteTimeout Duration `json:"write_timeout,omitempty" yaml:"write_timeout,omitempty"`
+	IdleTimeout  Duration `json:"idle_timeout,omitempty" yaml:"idle_timeout,omitempty"` //as for MaxIdleConnDuration
+
+	Retry *RetryConfig `json:"retry,omitempty" yaml:"retry,omitempty"`
+}
+
+//AuthConfig holds the credentials of a Config, at most one of Bearer and User may be set
+type AuthConfig struct {
+	Bearer   string `json:"bearer,omitempty" yaml:"bearer,omitempty"`
+	User     string `json:"user,omitempty" yaml:"user,omitempty"`
+	Password string `json:"password,omitempty" yaml:"password,omitempty"`
+	Digest   bool   `json:"digest,omitempty" yaml:"digest,omitempty"` //User and Password are for DigestAuth rather than BasicAuth
+}
+
+//RetryConfig is a RetryPolicy with durations that can be written as text
+type RetryConfig struct {
+	Max      int      `json:"max" yaml:"max"`
+	Base     Duration `json:"base,omitempty" yaml:"base,omitempty"`
+	MaxDelay Duration `json:"max_delay,omitempty" yaml:"max_delay,omitempty"`
+	Jitter   float64  `json:"jitter,omitempty" yaml:"jitter,omitempty"`
+}
+
+//Duration is a time.Duration read and written as text such as "1.5s" or "2m"
+type Duration time.Duration
+
+//UnmarshalText parses d with time.ParseDuration
+func (d *Duration) UnmarshalText(b []byte) error {
+	v, err := time.ParseDuration(string(b))
+	if err != nil {
+		return err
+	}
+	*d = Duration(v)
+	return nil
+}
+
+//MarshalText writes d as time.Duration's String does
+func (d Duration) MarshalText() ([]byte, error) {
+	return []byte(time.Duration(d).String()), nil
+}
+
+//Options returns the options that set up a Server as c describes, for use with NewServer(c.URL, ...) or SetOption
+func (c Config) Options() []func(*Server) error {
+	var opts []func(*Server) error
+	if len(c.Endpoints) > 0 {
+		opts = append(opts, Endpoints(c.Endpoints...))
+	}
+	if c.Balance != "" {
+		opts = append(opts, func(srv *Server) error {
+			b, err := parseBalancing(c.Balance)
+			if err != nil {
+				return err
+			}
+			return srv.setBalance(b)
+		})
+	}
+	if c.Cooldown != 0 {
+		opts = append(opts, FailoverCooldown(time.Duration(c.Cooldown)))
+	}
+	for k, v := range c.Headers {
+		opts = append(opts, Header(k, v))
+	}
+	if a := c.Auth; a != (AuthConfig{}) {
+		opts = append(opts, func(srv *Server) error {
+			switch {
+			case a.Bearer != "" && a.User != "":
+				return errors.New("jrc: auth cannot have both a bearer token and a user")
+			case a.Bearer != "":
+				return BearerToken(a.Bearer)(srv)
+			case a.Digest:
+				return DigestAuth(a.User, a.Password)(srv)
+			default:
+				return BasicAuth(a.User, a.Password)(srv)
+			}
+		})
+	}
+	if c.MaxCon != 0 {
+		opts = append(opts, MaxCon(c.MaxCon))
+	}
+	if c.MaxBatch != nil {
+		opts = append(opts, MaxBatch(*c.MaxBatch))
+	}
+	if c.QueueSize != 0 {
+		opts = append(opts, QueueSize(c.QueueSize))
+	}
+	if c.DialTimeout != 0 {
+		opts = append(opts, DialTimeout(time.Duration(c.DialTimeout)))
+	}
+	if c.ReadTimeout != 0 {
+		opts = append(opts, ReadTimeout(time.Duration(c.ReadTimeout)))
+	}
+	if c.WriteTimeout != 0 {
+		opts = append(opts, WriteTimeout(time.Duration(c.WriteTimeout)))
+	}
+	if c.IdleTimeout != 0 {
+		opts = append(opts, MaxIdleConnDuration(time.Duration(c.IdleTimeout)))
+	}
+	if r := c.Retry; r != nil {
+		opts = append(opts, Retry(RetryPolicy{Max: r.Max, Base: time.Duration(r.Base), MaxDelay: time.Duration(r.MaxDelay), Jitter: r.Jitter}))
+	}
+	return opts
+}
+
+func parseBalancing(s string) (Balancing, error) {
+	switch s {
+	case "priority":
+		return Priority, nil
+	case "round-robin":
+		return RoundRobin, nil
+	case "least-latency":
+		return LeastLatency, nil
+	}
+	return 0, errors.New("jrc: unknown balancing " + s)
+}
+
+//NewServerFromConfig creates a Server as c describes, applying options after those of c
+func NewServerFromConfig(c Config, options ...func(*Server) error) (*Server, error) {
+	if c.URL == "" {
+		return nil, errors.New("jrc: config has no url")
+	}
+	return NewServer(c.URL, append(c.Options(), options...)...)
+}
+
+//LoadConfig reads a Config from the JSON file at path
+//  a YAML file can be decoded into a Config with any YAML package that honours encoding.TextUnmarshaler, such as gopkg.in/yaml.v3
+func LoadConfig(path string) (Config, error) {
+	var c Config
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return c, err
+	}
+	if err := json.Unmarshal(b, &c); err != nil {
+		return c, errors.New("jrc: " + path + ": " + err.Error())
+	}
+	return c, nil
+}
@@ -0,0 +1,125 @@
+package jrc_test
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cfoxon/jrc"
+)
+
+func TestNewServerFromConfig(t *testing.T) {
+	var mu sync.Mutex
+	var auth []string
+	var posts int
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		posts++
+		n := posts
+		auth = append(auth, r.Header.Get("Authorization")+" "+r.Header.Get("X-Node"))
+		mu.Unlock()
+		if n == 1 {
+			//dropping the connection is a transport error, which is retried
+			c, _, _ := w.(http.Hijacker).Hijack()
+			c.Close()
+			return
+		}
+		b, _ := io.ReadAll(r.Body)
+		out := "["
+		for i := 1; i <= strings.Count(string(b), `"method"`); i++ {
+			if i > 1 {
+				out += ","
+			}
+			out += `{"jsonrpc":"2.0","id":` + string(rune('0'+i)) + `,"result":true}`
+		}
+		w.Write([]byte(out + "]"))
+	}))
+	defer ts.Close()
+
+	path := filepath.Join(t.TempDir(), "jrc.json")
+	os.WriteFile(path, []byte(`{
+		"url": "`+ts.URL+`",
+		"headers": {"X-Node": "a"},
+		"auth": {"bearer": "t0ken"},
+		"max_con": 2,
+		"max_batch": 0,
+		"read_timeout": "5s",
+		"retry": {"max": 1, "base": "1ms"}
+	}`), 0o600)
+	cfg, err := jrc.LoadConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if time.Duration(cfg.ReadTimeout) != 5*time.Second || cfg.MaxBatch == nil || *cfg.MaxBatch != 0 {
+		t.Fatalf("loaded %+v", cfg)
+	}
+	srv, err := jrc.NewServerFromConfig(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer srv.Close()
+
+	rs := jrc.RPCRequests{
+		{JsonRpc: "2.0", Id: 1, Method: "a"},
+		{JsonRpc: "2.0", Id: 2, Method: "b"},
+		{JsonRpc: "2.0", Id: 3, Method: "c"},
+	}
+	resps, err := srv.ExecBatch(rs)
+	if err != nil || len(resps) != 3 {
+		t.Fatal(resps, err)
+	}
+	//the dropped call was retried, and MaxBatch 0 sent the whole batch in one call
+	if posts != 2 || auth[1] != "Bearer t0ken a" {
+		t.Errorf("%d calls made with %q", posts, auth)
+	}
+
+	for _, bad := range []jrc.Config{
+		{},
+		{URL: ts.URL, Balance: "fastest"},
+		{URL: ts.URL, Auth: jrc.AuthConfig{Bearer: "t", User: "u"}},
+	} {
+		if _, err := jrc.NewServerFromConfig(bad); err == nil {
+			t.Errorf("%+v accepted", bad)
+		}
+	}
+	os.WriteFile(path, []byte(`{"url":"x","read_timeout":"soon"}`), 0o600)
+	if _, err := jrc.LoadConfig(path); err == nil {
+		t.Error("a bad duration was accepted")
+	}
+}
+
+func TestFromEnv(t *testing.T) {
+	var got string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Get("Authorization")
+		w.Write([]byte(`[{"jsonrpc":"2.0","id":1,"result":true}]`))
+	}))
+	defer ts.Close()
+	t.Setenv("JRC_URL", ts.URL)
+	t.Setenv("JRC_MAX_CONN", "3")
+	t.Setenv("JRC_TIMEOUT", "5s")
+	t.Setenv("JRC_AUTH_TOKEN", "t0ken")
+
+	srv, err := jrc.NewServer("", jrc.FromEnv())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer srv.Close()
+	if _, err := srv.Exec(jrc.RpcRequest{JsonRpc: "2.0", Id: 1, Method: "a"}); err != nil {
+		t.Fatal(err)
+	}
+	if got != "Bearer t0ken" {
+		t.Errorf("sent Authorization %q", got)
+	}
+
+	t.Setenv("JRC_MAX_CONN", "lots")
+	if _, err := jrc.NewServer("", jrc.FromEnv()); err == nil || !strings.Contains(err.Error(), "JRC_MAX_CONN") {
+		t.Errorf("bad JRC_MAX_CONN gave %v", err)
+	}
+}
@@ -0,0 +1,46 @@
+package jrc
+
+import (
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+
+	"github.com/valyala/fasthttp"
+)
+
+func (srv *Server) setCookieJar(jar http.CookieJar) error {
+	if jar == nil {
+		var err error
+		if jar, err = cookiejar.New(nil); err != nil {
+			return err
+		}
+	}
+	srv.jar = jar
+	return nil
+}
+
+//Cookies keeps the cookies set by the Server's responses in jar and sends them back with later requests,
+//  for services that log in with one call and expect a session cookie on the rest; nil for a new in-memory jar
+func Cookies(jar http.CookieJar) func(server *Server) error {
+	return func(srv *Server) error {
+		return srv.setCookieJar(jar)
+	}
+}
+
+//addCookies adds the cookies jar holds for u to req
+func addCookies(req *fasthttp.Request, jar http.CookieJar, u *url.URL) {
+	for _, c := range jar.Cookies(u) {
+		req.Header.SetCookie(c.Name, c.Value)
+	}
+}
+
+//storeCookies puts the cookies set by resp into jar
+func storeCookies(resp *fasthttp.Response, jar http.CookieJar, u *url.URL) {
+	var set []string
+	resp.Header.VisitAllCookie(func(_, value []byte) {
+		set = append(set, string(value))
+	})
+	if len(set) > 0 {
+		jar.SetCookies(u, (&http.Response{Header: http.Header{"Set-Cookie": set}}).Cookies())
+	}
+}
@@ -0,0 +1,71 @@
+package jrc
+
+//Correlate pairs each request with its response by id
+//  the result is aligned with rs and holds nil where the Server sent no response for a request
+//  requests sharing an id are matched to responses with that id in the order they arrived
+func Correlate(rs RPCRequests, resps []RpcResponse) []*RpcResponse {
+	out, _ := correlate(rs, resps)
+	return out
+}
+
+//ByID indexes resps by id, so the response to r is ByID(resps)[r.ID()] in whatever order the Server answered
+//  when responses share an id the first is kept, Correlate pairs each of them with a request instead
+func ByID(resps []RpcResponse) map[ID]*RpcResponse {
+	m := make(map[ID]*RpcResponse, len(resps))
+	for i := range resps {
+		if _, ok := m[resps[i].ID]; !ok {
+			m[resps[i].ID] = &resps[i]
+		}
+	}
+	return m
+}
+
+//correlate returns the aligned responses along with whether each response in resps was matched
+func correlate(rs RPCRequests, resps []RpcResponse) ([]*RpcResponse, []bool) {
+	byID := make(map[ID][]int, len(resps))
+	for i := range resps {
+		byID[resps[i].ID] = append(byID[resps[i].ID], i)
+	}
+	out := make([]*RpcResponse, len(rs))
+	used := make([]bool, len(resps))
+	for i, r := range rs {
+		id := r.ID()
+		if idx := byID[id]; len(idx) > 0 {
+			out[i] = &resps[idx[0]]
+			used[idx[0]] = true
+			byID[id] = idx[1:]
+		}
+	}
+	return out, used
+}
+
+//ordered sorts resps into the order of the requests in rs
+//  responses that match no request (such as parse errors with a null id) are kept at the end
+func ordered(rs RPCRequests, resps []RpcResponse) []RpcResponse {
+	matched, used := correlate(rs, resps)
+	out := make([]RpcResponse, 0, len(resps))
+	for _, r := range matched {
+		if r != nil {
+			out = append(out, *r)
+		}
+	}
+	for i, u := range used {
+		if !u {
+			out = append(out, resps[i])
+		}
+	}
+	return out
+}
+
+func (srv *Server) setPreserveOrder(b bool) error {
+	srv.order = b
+	return nil
+}
+
+//PreserveOrder makes ExecBatch return responses in the same order as the requests
+//  without it they come sub-batch by sub-batch as split by MaxBatch, each sub-batch in whatever order the server answered it
+func PreserveOrder(b bool) func(server *Server) error {
+	return func(srv *Server) error {
+		return srv.setPreserveOrder(b)
+	}
+}
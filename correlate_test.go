@@ -0,0 +1,158 @@
+package jrc_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/cfoxon/jrc"
+	"github.com/cfoxon/jrc/jrctest"
+	"github.com/goccy/go-json"
+)
+
+func TestByID(t *testing.T) {
+	ts := jrctest.NewServer()
+	defer ts.Close()
+	ts.Echo("echo")
+	srv, _ := ts.Client(jrc.MaxBatch(2))
+	defer srv.Close()
+	rs := jrc.RPCRequests{
+		{JsonRpc: "2.0", StrId: "a", Method: "echo", Params: []int{1}},
+		{JsonRpc: "2.0", Id: 7, Method: "echo", Params: []int{2}},
+		{JsonRpc: "2.0", StrId: "c", Method: "echo", Params: []int{3}},
+	}
+	resps, err := srv.ExecBatch(rs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	byID := jrc.ByID(resps)
+	for i, r := range rs {
+		resp := byID[r.ID()]
+		if resp == nil || string(resp.Result) != "["+string(rune('1'+i))+"]" {
+			t.Errorf("%v answered with %+v", r.ID(), resp)
+		}
+	}
+	if byID[jrc.IntID(8)] != nil {
+		t.Error("found a response to a request never sent")
+	}
+}
+
+func TestCheckIDs(t *testing.T) {
+	ts := jrctest.NewServer()
+	defer ts.Close()
+	ts.Echo("echo")
+	batch := func() jrc.RPCRequests {
+		return jrc.RPCRequests{
+			{JsonRpc: "2.0", Id: 1, Method: "echo", Params: []int{1}},
+			{JsonRpc: "2.0", Id: 2, Method: "echo", Params: []int{2}},
+			{JsonRpc: "2.0", Id: 1, Method: "echo", Params: []int{3}},
+		}
+	}
+
+	srv, _ := ts.Client(jrc.CheckIDs(jrc.RejectDuplicates))
+	defer srv.Close()
+	_, err := srv.ExecBatch(batch())
+	var de *jrc.DuplicateIDError
+	if !errors.As(err, &de) || de.ID != jrc.IntID(1) || de.First != 0 || de.Second != 2 {
+		t.Fatalf("got %v, want a DuplicateIDError", err)
+	}
+	if ts.Posts() != 0 {
+		t.Error("a batch with duplicate ids was sent")
+	}
+
+	srv.SetOption(jrc.CheckIDs(jrc.RenumberDuplicates))
+	rs := batch()
+	resps, err := srv.ExecBatch(rs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rs[2].ID() == jrc.IntID(1) || rs[2].ID() == jrc.IntID(2) {
+		t.Errorf("duplicate renumbered to %v", rs[2].ID())
+	}
+	if resp := jrc.ByID(resps)[rs[2].ID()]; resp == nil || string(resp.Result) != "[3]" {
+		t.Errorf("renumbered request answered with %+v", resp)
+	}
+
+	//a new id never takes one the caller set further on in the batch
+	next := 1
+	srv.SetOption(jrc.GenerateIDs(jrc.IDGeneratorFunc(func() jrc.ID {
+		next++
+		return jrc.IntID(next)
+	})))
+	rs = jrc.RPCRequests{
+		{JsonRpc: "2.0", Id: 1, Method: "echo", Params: []int{1}},
+		{JsonRpc: "2.0", Id: 1, Method: "echo", Params: []int{2}},
+		{JsonRpc: "2.0", Id: 2, Method: "echo", Params: []int{3}},
+	}
+	if _, err := srv.ExecBatch(rs); err != nil {
+		t.Fatal(err)
+	}
+	if rs[0].ID() != jrc.IntID(1) || rs[1].ID() != jrc.IntID(3) || rs[2].ID() != jrc.IntID(2) {
+		t.Errorf("ids became %v, %v, %v; want 1, 3, 2", rs[0].ID(), rs[1].ID(), rs[2].ID())
+	}
+}
+
+func TestSingleObjectResponses(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var rs []jrc.RpcRequest
+		json.NewDecoder(r.Body).Decode(&rs)
+		if len(rs) > 1 {
+			w.Write([]byte(`{"jsonrpc":"2.0","id":null,"error":{"code":-32600,"message":"batches are disabled"}}`))
+			return
+		}
+		//a batch of one is answered without the array
+		w.Write([]byte(` {"jsonrpc":"2.0","id":` + rs[0].ID().String() + `,"result":true}`))
+	}))
+	defer ts.Close()
+	srv, _ := jrc.NewServer(ts.URL, jrc.MaxBatch(2), jrc.PreserveOrder(true))
+	defer srv.Close()
+	rs := jrc.RPCRequests{
+		{JsonRpc: "2.0", Id: 1, Method: "a"},
+		{JsonRpc: "2.0", Id: 2, Method: "b"},
+		{JsonRpc: "2.0", Id: 3, Method: "c"},
+	}
+	resps, err := srv.ExecBatch(rs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resps) != 3 {
+		t.Fatalf("got %+v", resps)
+	}
+	for i, resp := range resps[:2] {
+		if resp.ID != jrc.IntID(i+1) || resp.Error == nil || resp.Error.Code != jrc.CodeInvalidRequest {
+			t.Errorf("request %d of the refused batch got %+v", i+1, resp)
+		}
+	}
+	if resps[2].ID != jrc.IntID(3) || string(resps[2].Result) != "true" {
+		t.Errorf("batch of one got %+v", resps[2])
+	}
+}
+
+func TestEmptyResponseToSingleCall(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`[]`))
+	}))
+	defer ts.Close()
+	srv, _ := jrc.NewServer(ts.URL)
+	defer srv.Close()
+	if resp, err := srv.Exec(jrc.RpcRequest{JsonRpc: "2.0", Id: 1, Method: "m"}); !errors.Is(err, jrc.ErrParse) {
+		t.Errorf("got %v, %v; want a ParseError", resp, err)
+	}
+}
+
+func TestStrictVersion1Errors(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"id":1,"result":null,"error":{"code":-32601,"message":"no such method"}}`))
+	}))
+	defer ts.Close()
+	srv, _ := jrc.NewServer(ts.URL, jrc.Version("1.0"), jrc.Strict(true))
+	defer srv.Close()
+	resp, err := srv.Exec(jrc.RpcRequest{Id: 1, Method: "m"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Error == nil || resp.Error.Code != -32601 || resp.Result != nil {
+		t.Errorf("got %+v", resp)
+	}
+}
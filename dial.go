@@ -0,0 +1,67 @@
+package jrc
+
+import (
+	"net"
+	"net/url"
+
+	"github.com/valyala/fasthttp"
+)
+
+//hostAddr returns the host:port to dial for u, adding the scheme's default port and keeping IPv6 literals bracketed
+func hostAddr(u *url.URL) string {
+	port := u.Port()
+	if port == "" {
+		port = "80"
+		if u.Scheme == "https" {
+			port = "443"
+		}
+	}
+	return net.JoinHostPort(u.Hostname(), port)
+}
+
+func (srv *Server) setDialDualStack(b bool) error {
+	srv.hc.DialDualStack = b
+	return nil
+}
+
+func (srv *Server) setDial(f fasthttp.DialFunc) error {
+	srv.hc.Dial = f
+	srv.dialLimit = 0
+	return nil
+}
+
+//DialDualStack sets whether both IPv4 and IPv6 addresses are tried when connecting, enabled by default
+//  it must stay enabled to reach IPv6 hosts, including literal addresses such as http://[::1]:8545
+func DialDualStack(b bool) func(server *Server) error {
+	return func(srv *Server) error {
+		return srv.setDialDualStack(b)
+	}
+}
+
+//Dial replaces how HTTP connections to the Server are opened, the function is passed the host:port to connect to
+func Dial(f fasthttp.DialFunc) func(server *Server) error {
+	return func(srv *Server) error {
+		return srv.setDial(f)
+	}
+}
+
+func (srv *Server) setHostClient(hc *fasthttp.HostClient) error {
+	if hc.Addr == "" {
+		hc.Addr = srv.hc.Addr
+		hc.IsTLS = srv.hc.IsTLS
+		if hc.Dial == nil {
+			hc.Dial = srv.hc.Dial
+		}
+	}
+	srv.hc = hc
+	return nil
+}
+
+//HostClient makes HTTP calls with hc, for full control over buffers, dialing, TLS and timeouts
+//  if hc.Addr is empty it is filled in from the Server's address
+//  options that change the client, such as TLSConfig or Proxy, must come after HostClient to apply to hc
+func HostClient(hc *fasthttp.HostClient) func(server *Server) error {
+	return func(srv *Server) error {
+		return srv.setHostClient(hc)
+	}
+}
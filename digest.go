@@ -0,0 +1,188 @@
+package jrc
+
+import (
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+//digestAuth answers HTTP digest challenges (RFC 7616), reusing the last nonce for later calls
+type digestAuth struct {
+	user string
+	pass string
+
+	mu        sync.Mutex
+	realm     string
+	nonce     string
+	opaque    string
+	qop       string //the one chosen from those offered, or empty for RFC 2069 servers
+	algorithm string
+	userhash  bool
+	nc        uint32
+}
+
+//DigestAuth authenticates with HTTP digest authentication, as needed by monerod and many embedded devices
+//  the first call is answered with a challenge and repeated with credentials, later calls reuse the nonce until the server renews it
+//  MD5, SHA-256 and SHA-512-256, their -sess variants and qop auth and auth-int are supported
+func DigestAuth(user, pass string) func(server *Server) error {
+	return func(srv *Server) error {
+		srv.digest = &digestAuth{user: user, pass: pass}
+		return nil
+	}
+}
+
+//challenge takes in the digest challenge of a 401 in err, reporting whether the call should be made again with it
+//  a call already sent with the current nonce is only repeated if the server says the nonce is stale
+func (d *digestAuth) challenge(err error) bool {
+	var se *StatusError
+	if !errors.As(err, &se) || se.StatusCode != http.StatusUnauthorized {
+		return false
+	}
+	for _, v := range se.Header.Values("Www-Authenticate") {
+		ps, ok := parseChallenge(v)
+		if !ok {
+			continue
+		}
+		if _, known := digestHash(ps["algorithm"]); !known {
+			continue
+		}
+		d.mu.Lock()
+		retry := ps["nonce"] != d.nonce || strings.EqualFold(ps["stale"], "true")
+		d.realm, d.nonce, d.opaque = ps["realm"], ps["nonce"], ps["opaque"]
+		d.algorithm = ps["algorithm"]
+		d.userhash = strings.EqualFold(ps["userhash"], "true")
+		d.qop = ""
+		for _, q := range strings.Split(ps["qop"], ",") {
+			if q = strings.TrimSpace(q); q == "auth" || q == "auth-int" && d.qop == "" {
+				d.qop = q
+			}
+		}
+		d.nc = 0
+		d.mu.Unlock()
+		return retry
+	}
+	return false
+}
+
+//authorize sets the Authorization header for m from the last challenge, doing nothing before the first one
+func (d *digestAuth) authorize(m *Message) error {
+	u, err := url.Parse(m.target())
+	if err != nil {
+		return err
+	}
+	d.mu.Lock()
+	if d.nonce == "" {
+		d.mu.Unlock()
+		return nil
+	}
+	d.nc++
+	realm, nonce, opaque, qop, algorithm, userhash, nc := d.realm, d.nonce, d.opaque, d.qop, d.algorithm, d.userhash, d.nc
+	d.mu.Unlock()
+
+	newHash, _ := digestHash(algorithm)
+	h := func(s string) string {
+		sum := newHash()
+		sum.Write([]byte(s))
+		return hex.EncodeToString(sum.Sum(nil))
+	}
+	var raw [16]byte
+	if _, err := rand.Read(raw[:]); err != nil {
+		return err
+	}
+	cnonce := hex.EncodeToString(raw[:])
+	count := fmt.Sprintf("%08x", nc)
+	uri := u.RequestURI()
+
+	ha1 := h(d.user + ":" + realm + ":" + d.pass)
+	if strings.HasSuffix(strings.ToLower(algorithm), "-sess") {
+		ha1 = h(ha1 + ":" + nonce + ":" + cnonce)
+	}
+	ha2 := h(m.method() + ":" + uri)
+	if qop == "auth-int" {
+		ha2 = h(m.method() + ":" + uri + ":" + h(string(m.payload())))
+	}
+	response := h(ha1 + ":" + nonce + ":" + ha2)
+	if qop != "" {
+		response = h(ha1 + ":" + nonce + ":" + count + ":" + cnonce + ":" + qop + ":" + ha2)
+	}
+
+	username := d.user
+	if userhash {
+		username = h(d.user + ":" + realm)
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, `Digest username=%q, realm=%q, nonce=%q, uri=%q, response=%q`, username, realm, nonce, uri, response)
+	if algorithm != "" {
+		b.WriteString(", algorithm=" + algorithm)
+	}
+	if opaque != "" {
+		fmt.Fprintf(&b, ", opaque=%q", opaque)
+	}
+	if qop != "" {
+		fmt.Fprintf(&b, ", qop=%s, nc=%s, cnonce=%q", qop, count, cnonce)
+	}
+	if userhash {
+		b.WriteString(", userhash=true")
+	}
+	if m.Header == nil {
+		m.Header = make(http.Header)
+	}
+	m.Header.Set("Authorization", b.String())
+	return nil
+}
+
+//digestHash returns the hash for a digest algorithm, MD5 when none is named
+func digestHash(algorithm string) (func() hash.Hash, bool) {
+	switch strings.ToUpper(strings.TrimSuffix(strings.ToLower(algorithm), "-sess")) {
+	case "", "MD5":
+		return md5.New, true
+	case "SHA-256":
+		return sha256.New, true
+	case "SHA-512-256":
+		return sha512.New512_256, true
+	}
+	return nil, false
+}
+
+//parseChallenge reads the parameters of a Digest WWW-Authenticate value, keys lowercased and quotes removed
+func parseChallenge(v string) (map[string]string, bool) {
+	scheme, rest, _ := strings.Cut(strings.TrimSpace(v), " ")
+	if !strings.EqualFold(scheme, "Digest") {
+		return nil, false
+	}
+	ps := make(map[string]string)
+	for rest = strings.TrimSpace(rest); rest != ""; rest = strings.TrimLeft(rest, ", ") {
+		var key string
+		key, rest, _ = strings.Cut(rest, "=")
+		key = strings.ToLower(strings.TrimSpace(key))
+		var val string
+		if strings.HasPrefix(rest, `"`) {
+			var sb strings.Builder
+			i := 1
+			for ; i < len(rest) && rest[i] != '"'; i++ {
+				if rest[i] == '\\' && i+1 < len(rest) {
+					i++
+				}
+				sb.WriteByte(rest[i])
+			}
+			if i < len(rest) {
+				i++
+			}
+			val, rest = sb.String(), rest[i:]
+		} else {
+			val, rest, _ = strings.Cut(rest, ",")
+			val = strings.TrimSpace(val)
+		}
+		ps[key] = val
+	}
+	return ps, ps["nonce"] != ""
+}
@@ -0,0 +1,99 @@
+package jrc_test
+
+import (
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	"hash"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"sync/atomic"
+	"testing"
+
+	"github.com/cfoxon/jrc"
+)
+
+//digestServer answers calls authorized for user:pass in realm rpc, and challenges the rest with the given algorithm
+func digestServer(t *testing.T, algorithm string, newHash func() hash.Hash, challenges *int32) *httptest.Server {
+	h := func(s string) string {
+		x := newHash()
+		x.Write([]byte(s))
+		return hex.EncodeToString(x.Sum(nil))
+	}
+	param := regexp.MustCompile(`(\w+)=(?:"([^"]*)"|([^,\s]*))`)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ps := map[string]string{}
+		for _, m := range param.FindAllStringSubmatch(r.Header.Get("Authorization"), -1) {
+			ps[m[1]] = m[2] + m[3]
+		}
+		ha1 := h("user:rpc:pass")
+		ha2 := h(r.Method + ":" + ps["uri"])
+		want := h(ha1 + ":n0nce:" + ps["nc"] + ":" + ps["cnonce"] + ":auth:" + ha2)
+		if ps["response"] != want || ps["nonce"] != "n0nce" || ps["uri"] != r.URL.RequestURI() || ps["opaque"] != "op" {
+			atomic.AddInt32(challenges, 1)
+			w.Header().Set("WWW-Authenticate", `Digest realm="rpc", qop="auth", algorithm=`+algorithm+`, nonce="n0nce", opaque="op"`)
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte(`[{"jsonrpc":"2.0","id":1,"result":true}]`))
+	}))
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+func TestDigestAuth(t *testing.T) {
+	cases := []struct {
+		algorithm string
+		newHash   func() hash.Hash
+		tr        jrc.Transport
+		get       bool
+	}{
+		{"MD5", md5.New, nil, false},
+		{"SHA-256", sha256.New, nil, false},
+		{"SHA-256", sha256.New, &jrc.NetHTTPTransport{}, false},
+		//a ReadOverGET call is signed as the GET it goes out as, query included
+		{"MD5", md5.New, nil, true},
+		{"MD5", md5.New, &jrc.NetHTTPTransport{}, true},
+	}
+	for _, c := range cases {
+		var challenges int32
+		ts := digestServer(t, c.algorithm, c.newHash, &challenges)
+		opts := []func(*jrc.Server) error{jrc.DigestAuth("user", "pass")}
+		if c.tr != nil {
+			opts = append(opts, jrc.UseTransport(c.tr))
+		}
+		if c.get {
+			opts = append(opts, jrc.ReadOverGET("q", jrc.GETBase64, "get_info"))
+		}
+		srv, err := jrc.NewServer(ts.URL+"/json_rpc?x=1", opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i := 0; i < 3; i++ {
+			resp, err := srv.Exec(jrc.RpcRequest{JsonRpc: "2.0", Id: 1, Method: "get_info"})
+			if err != nil || string(resp.Result) != "true" {
+				t.Fatalf("%s call %d: %v, %v", c.algorithm, i, resp, err)
+			}
+		}
+		//only the first call is challenged, later ones reuse the nonce
+		if challenges != 1 {
+			t.Errorf("%s: %d challenges, want 1", c.algorithm, challenges)
+		}
+		srv.Close()
+	}
+}
+
+func TestDigestAuthWrongPassword(t *testing.T) {
+	var challenges int32
+	ts := digestServer(t, "MD5", md5.New, &challenges)
+	srv, _ := jrc.NewServer(ts.URL, jrc.DigestAuth("user", "wrong"))
+	defer srv.Close()
+	if _, err := srv.Exec(jrc.RpcRequest{JsonRpc: "2.0", Id: 1, Method: "get_info"}); err == nil {
+		t.Fatal("call with the wrong password succeeded")
+	}
+	//the call is repeated once with credentials, not again and again
+	if challenges != 2 {
+		t.Errorf("%d challenges, want 2", challenges)
+	}
+}
@@ -0,0 +1,208 @@
+package jrc
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+//discoveryTimeout limits each lookup of the Server's endpoints
+const discoveryTimeout = 10 * time.Second
+
+//discovery finds the Server's endpoints with lookup every interval, in place of its address and Endpoints
+type discovery struct {
+	lookup   func(ctx context.Context) ([]*url.URL, error)
+	interval time.Duration
+	ctx      context.Context //cancelled to stop the lookups
+	cancel   context.CancelFunc
+	done     chan struct{} //closed once the lookups have stopped
+
+	mu     sync.Mutex
+	eps    []*endpoint //replaced as a whole, never changed in place, so callers may keep the slice they are given
+	closed bool
+}
+
+//endpoints returns those last found, nil before the first lookup succeeds or for a nil discovery
+func (d *discovery) endpoints() []*endpoint {
+	if d == nil {
+		return nil
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.eps
+}
+
+//active returns the endpoints calls go to, those discovered when there are any
+func (srv *Server) active() []*endpoint {
+	if eps := srv.discovery.endpoints(); len(eps) > 0 {
+		return eps
+	}
+	return srv.endpoints
+}
+
+func (srv *Server) setDiscovery(lookup func(ctx context.Context) ([]*url.URL, error), interval time.Duration) error {
+	if interval <= 0 {
+		return errors.New("jrc: discovery interval must be positive")
+	}
+	for _, ep := range srv.stopDiscovery() {
+		ep.close()
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	d := &discovery{lookup: lookup, interval: interval, ctx: ctx, cancel: cancel, done: make(chan struct{})}
+	if err := srv.refresh(d); err != nil {
+		cancel()
+		return err
+	}
+	srv.discovery = d
+	go srv.discover(d)
+	return nil
+}
+
+//stopDiscovery ends the lookups, if discovery is running, returning the endpoints they found for the caller to close
+//  srv.discovery is left in place as calls may still be reading it, with no endpoints from then on
+func (srv *Server) stopDiscovery() []*endpoint {
+	d := srv.discovery
+	if d == nil {
+		return nil
+	}
+	d.mu.Lock()
+	if d.closed {
+		d.mu.Unlock()
+		return nil
+	}
+	d.cancel()
+	eps := d.eps
+	d.eps, d.closed = nil, true
+	d.mu.Unlock()
+	<-d.done
+	return eps
+}
+
+func (srv *Server) discover(d *discovery) {
+	defer close(d.done)
+	t := time.NewTicker(d.interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-t.C:
+		case <-d.ctx.Done():
+			return
+		}
+		if err := srv.refresh(d); err != nil && d.ctx.Err() == nil {
+			srv.log(LogWarn, "jrc: endpoint discovery failed", "error", err)
+		}
+	}
+}
+
+//refresh looks up d's endpoints, keeping those still found so they keep their latency and cooldown
+//  the endpoints that are gone are closed, so calls in flight on their connections may fail over
+func (srv *Server) refresh(d *discovery) error {
+	ctx, cancel := context.WithTimeout(d.ctx, discoveryTimeout)
+	us, err := d.lookup(ctx)
+	cancel()
+	if err != nil {
+		return err
+	}
+	if len(us) == 0 {
+		//an empty answer is more likely a broken registry than no nodes at all, so the last ones are kept
+		return errors.New("jrc: no endpoints found")
+	}
+	d.mu.Lock()
+	old := make(map[string]*endpoint, len(d.eps))
+	for _, ep := range d.eps {
+		old[ep.url.String()] = ep
+	}
+	eps := make([]*endpoint, 0, len(us))
+	seen := make(map[string]bool, len(us))
+	for _, u := range us {
+		s := u.String()
+		if seen[s] {
+			continue
+		}
+		seen[s] = true
+		if ep, ok := old[s]; ok {
+			delete(old, s)
+			eps = append(eps, ep)
+			continue
+		}
+		eps = append(eps, srv.discoveredEndpoint(u))
+	}
+	var gone []*endpoint
+	if d.closed {
+		//the Server was closed during the lookup and has taken the endpoints there were, so all of these are new
+		gone = eps
+	} else {
+		d.eps = eps
+		for _, ep := range old {
+			gone = append(gone, ep)
+		}
+	}
+	d.mu.Unlock()
+	for _, ep := range gone {
+		ep.close()
+	}
+	return nil
+}
+
+//discoveredEndpoint creates an endpoint for u with the stream settings of the Server's own, as HandleIncoming,
+//  StreamFraming and Resubscribe only reach the endpoints there were when they were applied
+func (srv *Server) discoveredEndpoint(u *url.URL) *endpoint {
+	ep := srv.newEndpoint(u, false)
+	from, to := streamOf(srv.endpoints[0].tr), streamOf(ep.tr)
+	if from == nil || to == nil {
+		return ep
+	}
+	from.mu.Lock()
+	framing, incoming := from.framing, from.incoming
+	from.mu.Unlock()
+	to.framing, to.incoming = framing, incoming
+	if f, ok := srv.endpoints[0].tr.(*wsTransport); ok {
+		if t, ok := ep.tr.(*wsTransport); ok {
+			f.subMu.Lock()
+			t.resub = f.resub
+			f.subMu.Unlock()
+		}
+	}
+	return ep
+}
+
+//Discover sends calls to the nodes p lists, asking it again every interval so nodes can come and go
+//  an address without a scheme, such as host:port, takes the place of the host in the Server's address, whose scheme
+//  and path are kept, so Address must come before it; the nodes are tried in the order given, as Endpoints are
+//  the first lookup must succeed, after that a failed one is logged and the nodes last found are kept
+//  the address and Endpoints the Server was given are not called while the nodes found are
+func Discover(p EndpointProvider, interval time.Duration) func(server *Server) error {
+	return func(srv *Server) error {
+		base := *srv.url
+		return srv.setDiscovery(func(ctx context.Context) ([]*url.URL, error) {
+			addrs, err := p.Endpoints(ctx)
+			if err != nil {
+				return nil, err
+			}
+			us := make([]*url.URL, 0, len(addrs))
+			for _, addr := range addrs {
+				u := base
+				if strings.Contains(addr, "://") {
+					parsed, err := url.Parse(addr)
+					if err != nil {
+						return nil, err
+					}
+					u = *parsed
+				} else {
+					u.Host = addr
+				}
+				us = append(us, &u)
+			}
+			return us, nil
+		}, interval)
+	}
+}
+
+//DiscoverSRV sends calls to the nodes named by the DNS SRV records of _service._proto.name, looked up again every interval
+//  it is Discover with an SRVEndpoints, so the records' targets and ports go into the Server's address
+func DiscoverSRV(service, proto, name string, interval time.Duration) func(server *Server) error {
+	return Discover(SRVEndpoints{Service: service, Proto: proto, Name: name}, interval)
+}
@@ -0,0 +1,87 @@
+package jrc
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+//node answers every call with its name
+func node(t *testing.T, name string) *url.URL {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`[{"jsonrpc":"2.0","id":1,"result":"` + name + `"}]`))
+	}))
+	t.Cleanup(ts.Close)
+	u, _ := url.Parse(ts.URL)
+	return u
+}
+
+func TestDiscoverSRV(t *testing.T) {
+	a, b := node(t, "a"), node(t, "b")
+	var mu sync.Mutex
+	var records []*net.SRV
+	var fail error
+	answer := func(err error, us ...*url.URL) {
+		mu.Lock()
+		defer mu.Unlock()
+		records, fail = nil, err
+		for _, u := range us {
+			port, _ := strconv.Atoi(u.Port())
+			records = append(records, &net.SRV{Target: u.Hostname() + ".", Port: uint16(port)})
+		}
+	}
+	lookupSRV = func(_ context.Context, service, proto, name string) (string, []*net.SRV, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		if service != "jsonrpc" || proto != "tcp" || name != "nodes.example" {
+			t.Errorf("looked up _%s._%s.%s", service, proto, name)
+		}
+		return "", records, fail
+	}
+	defer func() { lookupSRV = net.DefaultResolver.LookupSRV }()
+	whoAnswers := func(srv *Server) string {
+		res, err := srv.Call(context.Background(), "name")
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(res)
+	}
+
+	answer(errors.New("no such host"))
+	if _, err := NewServer("http://placeholder/rpc", DiscoverSRV("jsonrpc", "tcp", "nodes.example", 10*time.Millisecond)); err == nil {
+		t.Fatal("a failed first lookup was accepted")
+	}
+
+	answer(nil, a)
+	srv, err := NewServer("http://placeholder/rpc", DiscoverSRV("jsonrpc", "tcp", "nodes.example", 10*time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer srv.Close()
+	if got := whoAnswers(srv); got != `"a"` {
+		t.Fatalf("%s answered, want a", got)
+	}
+	if states := srv.EndpointStates(); len(states) != 1 || states[0].URL != a.String()+"/rpc" {
+		t.Errorf("endpoints %+v", states)
+	}
+
+	//a node that leaves is dropped at the next refresh, and a failed lookup keeps the last nodes
+	answer(nil, b)
+	time.Sleep(50 * time.Millisecond)
+	answer(errors.New("timeout"))
+	time.Sleep(50 * time.Millisecond)
+	if got := whoAnswers(srv); got != `"b"` {
+		t.Fatalf("%s answered, want b", got)
+	}
+	srv.Close()
+	if len(srv.EndpointStates()) != 1 || srv.EndpointStates()[0].URL != "http://placeholder/rpc" {
+		t.Errorf("discovered endpoints kept after Close: %+v", srv.EndpointStates())
+	}
+}
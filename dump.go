@@ -0,0 +1,86 @@
+package jrc
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+//dumper writes every HTTP exchange to w for debugging
+type dumper struct {
+	mu     sync.Mutex
+	w      io.Writer
+	redact map[string]bool
+}
+
+//Dump writes the URL, headers and body of every HTTP request and the headers and body or error that came back to w
+//  the values of the headers named in redact are masked in both, for keeping credentials out of logs
+func Dump(w io.Writer, redact ...string) func(server *Server) error {
+	return func(srv *Server) error {
+		if w == nil {
+			srv.dump = nil
+			return nil
+		}
+		d := &dumper{w: w, redact: make(map[string]bool, len(redact))}
+		for _, h := range redact {
+			d.redact[http.CanonicalHeaderKey(h)] = true
+		}
+		srv.dump = d
+		return nil
+	}
+}
+
+func (d *dumper) write(m *Message, b []byte, err error, took time.Duration) {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, ">>> %s %s\n", m.method(), m.target())
+	d.headers(&buf, m.Header)
+	buf.WriteByte('\n')
+	//a ReadOverGET call has no body, as it is in the URL above
+	if enc := m.Header.Get("Content-Encoding"); enc != "" {
+		fmt.Fprintf(&buf, "(%d bytes of %s)\n", len(m.Body), enc)
+	} else if m.Query == nil {
+		buf.Write(m.Body)
+		buf.WriteByte('\n')
+	}
+	if err != nil {
+		fmt.Fprintf(&buf, "<<< error after %s: %v\n", took, err)
+	} else {
+		fmt.Fprintf(&buf, "<<< %d bytes in %s\n", len(b), took)
+	}
+	if len(m.Response) > 0 {
+		d.headers(&buf, m.Response)
+		buf.WriteByte('\n')
+	}
+	if se, ok := err.(*StatusError); ok && len(se.Body) > 0 {
+		buf.Write(bytes.TrimSpace(se.Body))
+		buf.WriteByte('\n')
+	} else if err == nil {
+		buf.Write(bytes.TrimSpace(b))
+		buf.WriteByte('\n')
+	}
+	buf.WriteByte('\n')
+	d.mu.Lock()
+	d.w.Write(buf.Bytes())
+	d.mu.Unlock()
+}
+
+//headers writes h to buf sorted by name, masking the values of redacted headers
+func (d *dumper) headers(buf *bytes.Buffer, h http.Header) {
+	keys := make([]string, 0, len(h))
+	for k := range h {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		for _, v := range h[k] {
+			if d.redact[http.CanonicalHeaderKey(k)] {
+				v = "[redacted]"
+			}
+			fmt.Fprintf(buf, "%s: %s\n", k, v)
+		}
+	}
+}
@@ -0,0 +1,57 @@
+package jrc_test
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/cfoxon/jrc"
+)
+
+func TestDumpWritesBothSidesOfTheExchange(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Request-Cost", "3")
+		w.Header().Set("Set-Cookie", "session=hush")
+		w.Write([]byte(`[{"jsonrpc":"2.0","id":1,"result":"done"}]`))
+	}))
+	defer ts.Close()
+	transports := map[string][]func(*jrc.Server) error{
+		"fasthttp": nil,
+		"net/http": {jrc.UseTransport(&jrc.NetHTTPTransport{})},
+	}
+	for name, opts := range transports {
+		t.Run(name, func(t *testing.T) {
+			var buf bytes.Buffer
+			opts = append(opts, jrc.BearerToken("secret"), jrc.Header("X-Foo", "bar"), jrc.Dump(&buf, "Authorization", "set-cookie"))
+			srv, err := jrc.NewServer(ts.URL, opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer srv.Close()
+			if _, err := srv.Exec(jrc.RpcRequest{JsonRpc: "2.0", Id: 1, Method: "m", Params: []int{5}}); err != nil {
+				t.Fatal(err)
+			}
+			dump := buf.String()
+			for _, want := range []string{">>> POST " + ts.URL, "X-Foo: bar", `"method":"m"`, "X-Request-Cost: 3", `"result":"done"`} {
+				if !strings.Contains(dump, want) {
+					t.Errorf("dump is missing %q:\n%s", want, dump)
+				}
+			}
+			if strings.Contains(dump, "secret") || strings.Contains(dump, "hush") {
+				t.Errorf("dump shows a redacted header:\n%s", dump)
+			}
+
+			//a ReadOverGET call is shown as the GET it goes out as, with no body
+			buf.Reset()
+			srv.SetOption(jrc.ReadOverGET("q", jrc.GETURLEncoded, "read"))
+			if _, err := srv.Exec(jrc.RpcRequest{JsonRpc: "2.0", Id: 1, Method: "read"}); err != nil {
+				t.Fatal(err)
+			}
+			if dump := buf.String(); !strings.Contains(dump, ">>> GET "+ts.URL+"/?q=") || strings.Contains(dump, `"method":"read"`) {
+				t.Errorf("dump of a GET:\n%s", dump)
+			}
+		})
+	}
+}
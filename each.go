@@ -0,0 +1,129 @@
+package jrc
+
+import (
+	"bytes"
+	"context"
+	stdjson "encoding/json"
+	"errors"
+	"io"
+)
+
+//ExecEach executes a single call whose Result is an array, decoding its elements one at a time into a T and passing each to fn
+//  unlike ExecTyped, neither a copy of the Result nor the whole slice is held in memory; an error from fn stops decoding
+func ExecEach[T any](ctx context.Context, srv *Server, r RpcRequest, fn func(T) error, opts ...CallOption) error {
+	bs, err := srv.ExecBatchFastContext(ctx, RPCRequests{&r}, opts...)
+	if err != nil {
+		return err
+	}
+	if len(bs) == 0 {
+		return errors.New("jrc: no response")
+	}
+	if !isJSON(srv.codec) {
+		//other encodings can't be read an element at a time
+		defer srv.Release(bs)
+		return eachDecoded(srv.codec, bs[0], fn)
+	}
+	err = EachResult(bs[0], fn)
+	var perr *ParseError
+	if errors.As(err, &perr) {
+		//bs is released below
+		perr.Body = append([]byte(nil), perr.Body...)
+	}
+	srv.Release(bs)
+	return err
+}
+
+//eachDecoded decodes the whole Result array in body with c, then passes its elements to fn
+func eachDecoded[T any](c Codec, body []byte, fn func(T) error) error {
+	resps, err := parseBatch(c, [][]byte{body}, nil)
+	if err != nil {
+		return err
+	}
+	if len(resps) == 0 {
+		return errors.New("jrc: no response")
+	}
+	if resps[0].Error != nil {
+		return resps[0].Error
+	}
+	var vs []T
+	if len(resps[0].Result) > 0 {
+		if err := c.Unmarshal(resps[0].Result, &vs); err != nil {
+			return &ParseError{Body: append([]byte(nil), body...), Err: err}
+		}
+	}
+	for _, v := range vs {
+		if err := fn(v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+//EachResult decodes the elements of the Result array in body, a response from ExecBatchFast holding one response, into a T one at a time
+//  an RpcError in the response is returned as the error
+func EachResult[T any](body []byte, fn func(T) error) error {
+	dec := stdjson.NewDecoder(bytes.NewReader(body))
+	tok, err := dec.Token()
+	if err != nil {
+		return &ParseError{Body: body, Err: err}
+	}
+	if tok == stdjson.Delim('[') {
+		if tok, err = dec.Token(); err != nil {
+			return &ParseError{Body: body, Err: err}
+		}
+	}
+	if tok != stdjson.Delim('{') {
+		return &ParseError{Body: body, Err: errors.New("jrc: response is not an object")}
+	}
+	for dec.More() {
+		key, err := dec.Token()
+		if err != nil {
+			return &ParseError{Body: body, Err: err}
+		}
+		switch key {
+		case "result":
+			return eachElement(dec, body, fn)
+		case "error":
+			var e *RpcError
+			if err := dec.Decode(&e); err != nil {
+				return &ParseError{Body: body, Err: err}
+			}
+			if e != nil {
+				return e
+			}
+		default:
+			var skip stdjson.RawMessage
+			if err := dec.Decode(&skip); err != nil {
+				return &ParseError{Body: body, Err: err}
+			}
+		}
+	}
+	return nil
+}
+
+//eachElement decodes the array dec is positioned at, passing each element to fn
+func eachElement[T any](dec *stdjson.Decoder, body []byte, fn func(T) error) error {
+	tok, err := dec.Token()
+	if err != nil {
+		return &ParseError{Body: body, Err: err}
+	}
+	if tok == nil {
+		return nil
+	}
+	if tok != stdjson.Delim('[') {
+		return &ParseError{Body: body, Err: errors.New("jrc: result is not an array")}
+	}
+	for dec.More() {
+		var v T
+		if err := dec.Decode(&v); err != nil {
+			return &ParseError{Body: body, Err: err}
+		}
+		if err := fn(v); err != nil {
+			return err
+		}
+	}
+	if _, err := dec.Token(); err != nil && err != io.EOF {
+		return &ParseError{Body: body, Err: err}
+	}
+	return nil
+}
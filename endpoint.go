@@ -0,0 +1,308 @@
+package jrc
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/valyala/fasthttp"
+)
+
+//endpoint is one address of the Server along with the transport its scheme calls for
+type endpoint struct {
+	url *url.URL
+	tr  Transport
+
+	mu        sync.Mutex
+	downUntil time.Time
+	latency   time.Duration //moving average of successful calls, zero until the first one
+	lastCheck time.Time
+	healthErr error //from the last health check
+}
+
+//newEndpoint creates an endpoint for u, the primary endpoint uses the Server's own HostClient
+func (srv *Server) newEndpoint(u *url.URL, primary bool) *endpoint {
+	ep := &endpoint{url: u}
+	switch u.Scheme {
+	case "tcp":
+		ep.tr = newStreamTransport("tcp", u.Host)
+	case "ipc":
+		ep.tr = newStreamTransport("unix", u.Path)
+	case "ws", "wss":
+		ep.tr = newWSTransport(srv, u)
+	default:
+		if primary {
+			ep.tr = &fasthttpTransport{srv: srv}
+		} else {
+			ep.tr = &fasthttpTransport{srv: srv, u: u}
+		}
+	}
+	return ep
+}
+
+//available reports whether the endpoint passed its last health check and has finished cooling down after its last failure
+func (ep *endpoint) available(now time.Time) bool {
+	ep.mu.Lock()
+	defer ep.mu.Unlock()
+	return ep.healthErr == nil && !now.Before(ep.downUntil)
+}
+
+//fail takes the endpoint out of rotation for d
+func (ep *endpoint) fail(d time.Duration) {
+	ep.mu.Lock()
+	ep.downUntil = time.Now().Add(d)
+	ep.mu.Unlock()
+}
+
+//observe folds the duration of a successful call into the endpoint's average latency
+func (ep *endpoint) observe(d time.Duration) {
+	ep.mu.Lock()
+	defer ep.mu.Unlock()
+	if ep.latency == 0 {
+		ep.latency = d
+		return
+	}
+	ep.latency += (d - ep.latency) / 5
+}
+
+func (ep *endpoint) averageLatency() time.Duration {
+	ep.mu.Lock()
+	defer ep.mu.Unlock()
+	return ep.latency
+}
+
+func (ep *endpoint) close() error {
+	if c, ok := ep.tr.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
+
+//candidates returns the endpoints p allows in the order the Server's Balancing calls for
+//  unhealthy endpoints and those cooling down after a failure are skipped, unless all of them are
+func (srv *Server) candidates(p *profile) []*endpoint {
+	now := time.Now()
+	eps := p.filter(srv.active())
+	up := make([]*endpoint, 0, len(eps))
+	for _, ep := range eps {
+		if ep.available(now) {
+			up = append(up, ep)
+		}
+	}
+	if len(up) == 0 {
+		up = append(up, eps...)
+	}
+	if len(up) < 2 {
+		return up
+	}
+	switch srv.balance {
+	case RoundRobin:
+		n := int(atomic.AddUint32(&srv.next, 1) % uint32(len(up)))
+		up = append(append(make([]*endpoint, 0, len(up)), up[n:]...), up[:n]...)
+	case LeastLatency:
+		//endpoints without a measurement yet sort first so they get one
+		sort.SliceStable(up, func(i, j int) bool {
+			return up[i].averageLatency() < up[j].averageLatency()
+		})
+	}
+	return up
+}
+
+//failover sends c to each candidate endpoint in turn until one succeeds
+func (srv *Server) failover(c *call) (b []byte, err error) {
+	eps := srv.candidates(c.msg.profile)
+	if len(eps) == 0 {
+		return nil, errNoProfileEndpoint
+	}
+	if srv.hedge > 0 && len(eps) > 1 && !c.msg.Notify {
+		return srv.hedged(c, eps)
+	}
+	for _, ep := range eps {
+		c.msg.tally.sent()
+		if b, err = srv.send(c.ctx, ep, c.msg); err == nil || done(c.ctx) != nil {
+			return b, err
+		}
+	}
+	return b, err
+}
+
+//send delivers m to ep once the rate limit allows, tracking its latency or putting it into cooldown
+func (srv *Server) send(ctx context.Context, ep *endpoint, m *Message) (b []byte, err error) {
+	if srv.limit != nil {
+		if err := srv.limit.wait(ctx); err != nil {
+			return nil, err
+		}
+	}
+	t := srv.tr
+	if t == nil {
+		t = ep.tr
+	}
+	m.URL = requestURL(ep.url, m.path)
+	srv.log(LogDebug, "jrc: sending", "url", m.URL, "bytes", len(m.Body))
+	if srv.tracer != nil {
+		var end func(error)
+		ctx, end = srv.tracer.StartCall(ctx, m)
+		defer func() { end(err) }()
+	}
+	if srv.dump != nil {
+		m.Response = make(http.Header)
+	}
+	start := time.Now()
+	b, err = srv.deliver(ctx, t, m)
+	if srv.dump != nil {
+		srv.dump.write(m, b, err, time.Since(start))
+	}
+	if srv.adapt != nil && !m.Notify && ctx.Err() == nil {
+		srv.adapt.observe(m.Requests, time.Since(start), err)
+	}
+	if err == nil {
+		ep.observe(time.Since(start))
+	} else if done(ctx) == nil {
+		//a done context means the caller gave up, which says nothing about the endpoint
+		srv.log(LogWarn, "jrc: endpoint failed", "url", m.URL, "error", err, "cooldown", srv.cooldown)
+		ep.fail(srv.cooldown)
+	}
+	return b, err
+}
+
+//deliver sends m over t once it is authenticated for its URL, answering a digest challenge by sending it again
+func (srv *Server) deliver(ctx context.Context, t Transport, m *Message) ([]byte, error) {
+	if err := srv.authenticate(ctx, m); err != nil {
+		return nil, err
+	}
+	m.Challenge = srv.digest != nil
+	b, err := t.RoundTrip(ctx, m)
+	if srv.digest != nil && srv.digest.challenge(err) {
+		if err := srv.authenticate(ctx, m); err != nil {
+			return nil, err
+		}
+		if m.Response != nil {
+			m.Response = make(http.Header)
+		}
+		b, err = t.RoundTrip(ctx, m)
+	}
+	return b, err
+}
+
+//authenticate signs m or answers the last digest challenge, as set up for the Server
+func (srv *Server) authenticate(ctx context.Context, m *Message) error {
+	if srv.digest != nil {
+		if err := srv.digest.authorize(m); err != nil {
+			return err
+		}
+	}
+	if srv.sign != nil {
+		return srv.sign(ctx, m)
+	}
+	return nil
+}
+
+func (srv *Server) addEndpoints(addrs []string) error {
+	for _, addr := range addrs {
+		u, err := url.Parse(addr)
+		if err != nil {
+			return err
+		}
+		srv.endpoints = append(srv.endpoints, srv.newEndpoint(u, false))
+	}
+	return nil
+}
+
+func (srv *Server) setCooldown(d time.Duration) error {
+	if d < 0 {
+		return errors.New("jrc: cooldown cannot be negative")
+	}
+	srv.cooldown = d
+	return nil
+}
+
+//Endpoints adds fallback addresses, tried in order when the ones before them fail with transport errors or 5xx responses
+func Endpoints(addrs ...string) func(server *Server) error {
+	return func(srv *Server) error {
+		return srv.addEndpoints(addrs)
+	}
+}
+
+//FailoverCooldown sets how long a failed endpoint is skipped before it is tried again, 10 seconds by default
+func FailoverCooldown(d time.Duration) func(server *Server) error {
+	return func(srv *Server) error {
+		return srv.setCooldown(d)
+	}
+}
+
+//Balancing selects how calls are spread across the Server's endpoints
+type Balancing int
+
+const (
+	//Priority sends every call to the first available endpoint, the others are only used for failover
+	Priority Balancing = iota
+	//RoundRobin rotates through the available endpoints, so sub-batches are spread across all of them
+	RoundRobin
+	//LeastLatency prefers the available endpoint with the lowest average response time
+	LeastLatency
+)
+
+func (srv *Server) setBalance(b Balancing) error {
+	if b < Priority || b > LeastLatency {
+		return errors.New("jrc: unknown balancing")
+	}
+	srv.balance = b
+	return nil
+}
+
+//Balance sets how calls are spread across the Server's endpoints, Priority by default
+//  whichever is used, a failing endpoint is still skipped for the FailoverCooldown
+func Balance(b Balancing) func(server *Server) error {
+	return func(srv *Server) error {
+		return srv.setBalance(b)
+	}
+}
+
+//configureHostClient points hc at u
+func configureHostClient(hc *fasthttp.HostClient, u *url.URL) {
+	hc.Addr = hostAddr(u)
+	hc.IsTLS = u.Scheme == "https"
+	if u.Scheme == "unix" {
+		hc.Addr = u.Path
+		hc.Dial = unixDialer(u.Path)
+	}
+}
+
+//cloneHostClient copies the settings of hc, a client for from, into a new client for u
+//  the dialer of a client for a unix socket is left out, as it only reaches that socket
+func cloneHostClient(hc *fasthttp.HostClient, from, u *url.URL) *fasthttp.HostClient {
+	c := &fasthttp.HostClient{
+		Name:                          hc.Name,
+		NoDefaultUserAgentHeader:      hc.NoDefaultUserAgentHeader,
+		Dial:                          hc.Dial,
+		DialDualStack:                 hc.DialDualStack,
+		TLSConfig:                     hc.TLSConfig,
+		MaxConns:                      hc.MaxConns,
+		MaxConnDuration:               hc.MaxConnDuration,
+		MaxIdleConnDuration:           hc.MaxIdleConnDuration,
+		MaxIdemponentCallAttempts:     hc.MaxIdemponentCallAttempts,
+		ReadBufferSize:                hc.ReadBufferSize,
+		WriteBufferSize:               hc.WriteBufferSize,
+		ReadTimeout:                   hc.ReadTimeout,
+		WriteTimeout:                  hc.WriteTimeout,
+		MaxResponseBodySize:           hc.MaxResponseBodySize,
+		DisableHeaderNamesNormalizing: hc.DisableHeaderNamesNormalizing,
+		DisablePathNormalizing:        hc.DisablePathNormalizing,
+		SecureErrorLogMessage:         hc.SecureErrorLogMessage,
+		MaxConnWaitTimeout:            hc.MaxConnWaitTimeout,
+		RetryIf:                       hc.RetryIf,
+		Transport:                     hc.Transport,
+		ConnPoolStrategy:              hc.ConnPoolStrategy,
+	}
+	if from != nil && from.Scheme == "unix" {
+		c.Dial = nil
+	}
+	configureHostClient(c, u)
+	return c
+}
@@ -0,0 +1,85 @@
+package jrc
+
+import (
+	"errors"
+	"os"
+	"strconv"
+	"strings"
+)
+
+//FromEnv sets up the Server from whichever of these environment variables are set, as for the Config fields they name
+//  JRC_URL, JRC_ENDPOINTS (comma separated), JRC_BALANCE, JRC_MAX_CONN, JRC_MAX_BATCH, JRC_QUEUE_SIZE,
+//  JRC_TIMEOUT (the ReadTimeout), JRC_DIAL_TIMEOUT, JRC_WRITE_TIMEOUT, JRC_IDLE_TIMEOUT,
+//  JRC_AUTH_TOKEN (a bearer token), JRC_USER and JRC_PASSWORD (basic auth), JRC_RETRY_MAX and JRC_RETRY_BASE
+//  with JRC_URL set, NewServer("", FromEnv()) takes its address from the environment
+func FromEnv() func(server *Server) error {
+	return func(srv *Server) error {
+		c, err := envConfig(os.LookupEnv)
+		if err != nil {
+			return err
+		}
+		if c.URL != "" {
+			if err := srv.setAddress(c.URL); err != nil {
+				return err
+			}
+		}
+		for _, opt := range c.Options() {
+			if err := opt(srv); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+//envConfig reads a Config from the variables lookup finds
+func envConfig(lookup func(string) (string, bool)) (Config, error) {
+	var c Config
+	var err error
+	str := func(name string, dst *string) {
+		if v, ok := lookup(name); ok {
+			*dst = v
+		}
+	}
+	num := func(name string, dst *int) {
+		if v, ok := lookup(name); ok && err == nil {
+			if *dst, err = strconv.Atoi(v); err != nil {
+				err = errors.New("jrc: " + name + " is not a number: " + v)
+			}
+		}
+	}
+	dur := func(name string, dst *Duration) {
+		if v, ok := lookup(name); ok && err == nil {
+			if dst.UnmarshalText([]byte(v)) != nil {
+				err = errors.New("jrc: " + name + " is not a duration: " + v)
+			}
+		}
+	}
+
+	str("JRC_URL", &c.URL)
+	if v, ok := lookup("JRC_ENDPOINTS"); ok && v != "" {
+		for _, addr := range strings.Split(v, ",") {
+			c.Endpoints = append(c.Endpoints, strings.TrimSpace(addr))
+		}
+	}
+	str("JRC_BALANCE", &c.Balance)
+	num("JRC_MAX_CONN", &c.MaxCon)
+	if _, ok := lookup("JRC_MAX_BATCH"); ok {
+		c.MaxBatch = new(int)
+		num("JRC_MAX_BATCH", c.MaxBatch)
+	}
+	num("JRC_QUEUE_SIZE", &c.QueueSize)
+	dur("JRC_TIMEOUT", &c.ReadTimeout)
+	dur("JRC_DIAL_TIMEOUT", &c.DialTimeout)
+	dur("JRC_WRITE_TIMEOUT", &c.WriteTimeout)
+	dur("JRC_IDLE_TIMEOUT", &c.IdleTimeout)
+	str("JRC_AUTH_TOKEN", &c.Auth.Bearer)
+	str("JRC_USER", &c.Auth.User)
+	str("JRC_PASSWORD", &c.Auth.Password)
+	if _, ok := lookup("JRC_RETRY_MAX"); ok {
+		c.Retry = &RetryConfig{}
+		num("JRC_RETRY_MAX", &c.Retry.Max)
+		dur("JRC_RETRY_BASE", &c.Retry.Base)
+	}
+	return c, err
+}
@@ -0,0 +1,139 @@
+package jrc
+
+import (
+	"context"
+	"errors"
+	"net"
+
+	"github.com/cfoxon/jrc/internal/json"
+)
+
+//Standard JSON RPC 2.0 error codes
+//  codes from -32000 to -32099 are left to servers for their own errors, see IsServerErrorCode
+const (
+	CodeParseError     = -32700
+	CodeInvalidRequest = -32600
+	CodeMethodNotFound = -32601
+	CodeInvalidParams  = -32602
+	CodeInternalError  = -32603
+)
+
+//IsServerErrorCode reports whether code is in the range reserved for implementation defined server errors
+func IsServerErrorCode(code int) bool {
+	return code <= -32000 && code >= -32099
+}
+
+//Sentinels for the kinds of failure, to test for with errors.Is
+var (
+	ErrTransport = errors.New("jrc: transport failure") //matched by a *TransportError
+	ErrStatus    = errors.New("jrc: HTTP status error") //matched by a *StatusError
+	ErrParse     = errors.New("jrc: invalid response")  //matched by a *ParseError
+	ErrTimeout   = errors.New("jrc: timeout")           //matched by a *TransportError that timed out
+)
+
+//TransportError is a call that did not get a response from any endpoint, for reasons other than an HTTP status
+type TransportError struct {
+	Err error
+}
+
+//Error implements the error interface
+func (e *TransportError) Error() string {
+	return "jrc: transport: " + e.Err.Error()
+}
+
+//Unwrap returns the underlying error
+func (e *TransportError) Unwrap() error {
+	return e.Err
+}
+
+//Is matches ErrTransport, and ErrTimeout if the call timed out
+func (e *TransportError) Is(target error) bool {
+	return target == ErrTransport || target == ErrTimeout && isTimeout(e.Err)
+}
+
+func isTimeout(err error) bool {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	var ne net.Error
+	if errors.As(err, &ne) && ne.Timeout() {
+		return true
+	}
+	var te interface{ Timeout() bool }
+	return errors.As(err, &te) && te.Timeout()
+}
+
+//wrapTransport marks a worker's failed call as a TransportError, unless it is already of a kind of its own
+func wrapTransport(err error) error {
+	var se *StatusError
+	switch {
+	case err == nil, err == ErrCircuitOpen, err == ErrClosed, errors.As(err, &se), errors.Is(err, context.Canceled):
+		return err
+	}
+	return &TransportError{Err: err}
+}
+
+//ParseError is a response body that could not be decoded
+type ParseError struct {
+	Body []byte
+	Err  error
+}
+
+//Error implements the error interface
+func (e *ParseError) Error() string {
+	return e.Err.Error() + "\n" + string(e.Body)
+}
+
+//Unwrap returns the decoding error
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+//Is matches ErrParse
+func (e *ParseError) Is(target error) bool {
+	return target == ErrParse
+}
+
+//Is matches ErrStatus
+func (e *StatusError) Is(target error) bool {
+	return target == ErrStatus
+}
+
+//Is matches another *RpcError with the same Code, so errors.Is(err, &RpcError{Code: CodeMethodNotFound}) works
+func (e *RpcError) Is(target error) bool {
+	t, ok := target.(*RpcError)
+	return ok && t.Code == e.Code
+}
+
+//Is reports whether the error of any failed call matches target
+func (e *BatchError) Is(target error) bool {
+	for i := range e.Failed {
+		if errors.Is(&e.Failed[i], target) {
+			return true
+		}
+	}
+	return false
+}
+
+//As finds the first error of a failed call that matches target, as errors.As does
+func (e *BatchError) As(target interface{}) bool {
+	for i := range e.Failed {
+		if errors.As(&e.Failed[i], target) {
+			return true
+		}
+	}
+	return false
+}
+
+//DataAs decodes the Data of the error into v, which must be a pointer, for servers that send structured error details
+//  it does nothing when there is no Data
+func (e *RpcError) DataAs(v interface{}) error {
+	if e.Data == nil {
+		return nil
+	}
+	b, err := json.Marshal(e.Data)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, v)
+}
@@ -0,0 +1,52 @@
+package jrc
+
+import (
+	"errors"
+	"sync/atomic"
+)
+
+func (srv *Server) setFallback(b bool) error {
+	srv.fallback = b
+	if !b {
+		atomic.StoreUint32(&srv.unbatched, 0)
+	}
+	return nil
+}
+
+//FallbackToSingle watches for a Server refusing batches, as many Hive and appbase nodes do, and from then on sends every
+//  request as an HTTP call of its own, as a bare object, the requests of the refused batch being sent again that way
+//  a batch is taken as refused when a sub-batch of several requests is answered with a single error with a null id or
+//  with a body that is not JSON RPC at all; ExecBatch, Exec and ExecBatchStream fall back, ExecBatchFast only splits
+//  batches that come after; MaxCon still limits how many calls are in flight and turning it off batches again
+func FallbackToSingle(b bool) func(server *Server) error {
+	return func(srv *Server) error {
+		return srv.setFallback(b)
+	}
+}
+
+//isUnbatched reports whether FallbackToSingle has found that the Server refuses batches
+func (srv *Server) isUnbatched() bool {
+	return atomic.LoadUint32(&srv.unbatched) == 1
+}
+
+//refused reports whether the responses to rs, parsed with err, show the Server refusing the batch, for FallbackToSingle
+//  in which case it stops batching; wasUnbatched is whether it had stopped before the batch was sent, as a request on
+//  its own will then have gone as a bare object, and otherwise in an array, refused along with the rest of its batch
+func (srv *Server) refused(rs RPCRequests, resps []RpcResponse, err error, wasUnbatched bool) bool {
+	if !srv.fallback || len(rs) == 0 {
+		return false
+	}
+	var pe *ParseError
+	envelope := len(resps) == 1 && resps[0].ID.IsNull() && resps[0].Error != nil
+	if !envelope && !errors.As(err, &pe) {
+		return false
+	}
+	if len(rs) == 1 {
+		//a single error says nothing of batches, unless a sub-batch of several has been refused meanwhile
+		return !wasUnbatched && srv.isUnbatched()
+	}
+	if atomic.CompareAndSwapUint32(&srv.unbatched, 0, 1) {
+		srv.log(LogWarn, "jrc: batch refused, sending requests one at a time", "requests", len(rs))
+	}
+	return true
+}
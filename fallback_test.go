@@ -0,0 +1,149 @@
+package jrc_test
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/cfoxon/jrc"
+	"github.com/goccy/go-json"
+)
+
+func TestFallbackToSingle(t *testing.T) {
+	var posts, arrays int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&posts, 1)
+		b, _ := io.ReadAll(r.Body)
+		if strings.HasPrefix(string(b), "[") {
+			//arrays are refused, even of one request
+			atomic.AddInt32(&arrays, 1)
+			w.Write([]byte(`{"jsonrpc":"2.0","id":null,"error":{"code":-32600,"message":"batch requests are not supported"}}`))
+			return
+		}
+		var q jrc.RpcRequest
+		json.Unmarshal(b, &q)
+		w.Write([]byte(`{"jsonrpc":"2.0","id":` + q.ID().String() + `,"result":"` + q.Method + `"}`))
+	}))
+	defer ts.Close()
+	srv, _ := jrc.NewServer(ts.URL, jrc.MaxBatch(2), jrc.MaxCon(1), jrc.PreserveOrder(true), jrc.FallbackToSingle(true))
+	defer srv.Close()
+	rs := jrc.RPCRequests{
+		{JsonRpc: "2.0", Id: 1, Method: "a"},
+		{JsonRpc: "2.0", Id: 2, Method: "b"},
+		{JsonRpc: "2.0", Id: 3, Method: "c"},
+	}
+	resps, err := srv.ExecBatch(rs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, resp := range resps {
+		if resp.ID != rs[i].ID() || string(resp.Result) != `"`+rs[i].Method+`"` {
+			t.Errorf("request %d got %+v", i+1, resp)
+		}
+	}
+	//the two sub-batches were refused and their requests then sent one at a time
+	if posts != 5 || arrays != 2 {
+		t.Errorf("%d calls made, %d of them arrays", posts, arrays)
+	}
+
+	//later batches go out unbatched from the start
+	var got []string
+	err = srv.ExecBatchStream(rs, func(resp jrc.RpcResponse) error {
+		got = append(got, string(resp.Result))
+		return nil
+	})
+	if err != nil || len(got) != 3 || posts != 8 || arrays != 2 {
+		t.Errorf("streamed %v, %v with %d calls made, %d of them arrays", got, err, posts, arrays)
+	}
+}
+
+func TestBareSingle(t *testing.T) {
+	var bodies []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		bodies = append(bodies, string(b))
+		if b[0] == '[' {
+			w.Write([]byte(`[{"jsonrpc":"2.0","id":1,"result":1},{"jsonrpc":"2.0","id":2,"result":2}]`))
+			return
+		}
+		w.Write([]byte(`{"jsonrpc":"2.0","id":1,"result":1}`))
+	}))
+	defer ts.Close()
+	srv, _ := jrc.NewServer(ts.URL, jrc.BareSingle(true))
+	defer srv.Close()
+	resp, err := srv.Exec(jrc.RpcRequest{JsonRpc: "2.0", Id: 1, Method: "a"})
+	if err != nil || string(resp.Result) != "1" {
+		t.Fatal(resp, err)
+	}
+	//batches are still sent as arrays
+	if _, err := srv.ExecBatch(jrc.RPCRequests{{JsonRpc: "2.0", Id: 1, Method: "a"}, {JsonRpc: "2.0", Id: 2, Method: "b"}}); err != nil {
+		t.Fatal(err)
+	}
+	if len(bodies) != 2 || bodies[0][0] != '{' || bodies[1][0] != '[' {
+		t.Errorf("sent %q", bodies)
+	}
+}
+
+//callRecorder keeps the CallStats of every call
+type callRecorder struct {
+	mu    sync.Mutex
+	calls []jrc.CallStats
+}
+
+func (r *callRecorder) ObserveCall(s *jrc.CallStats) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.calls = append(r.calls, *s)
+}
+
+func TestBareSingleMetrics(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"jsonrpc":"2.0","id":1,"error":{"code":-32000,"message":"busy"}}`))
+	}))
+	defer ts.Close()
+	var rec callRecorder
+	srv, _ := jrc.NewServer(ts.URL, jrc.BareSingle(true), jrc.Instrument(&rec))
+	defer srv.Close()
+	srv.Exec(jrc.RpcRequest{JsonRpc: "2.0", Id: 1, Method: "a"})
+	if len(rec.calls) != 1 {
+		t.Fatalf("observed %d calls", len(rec.calls))
+	}
+	if s := rec.calls[0]; len(s.Methods) != 1 || s.Methods[0] != "a" || len(s.RPCErrors) != 1 || s.RPCErrors[0].Code != -32000 {
+		t.Errorf("stats of a bare call %+v", s)
+	}
+}
+
+func TestHealthCheckShape(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		switch {
+		case strings.HasPrefix(string(b), "["):
+			w.Write([]byte(`{"jsonrpc":"2.0","id":null,"error":{"code":-32600,"message":"batches are not supported"}}`))
+		case strings.Contains(string(b), `"jsonrpc"`):
+			w.Write([]byte(`{"jsonrpc":"2.0","id":1,"result":"ok"}`))
+		default:
+			w.Write([]byte(`{"id":1,"result":"ok","error":null}`))
+		}
+	}))
+	defer ts.Close()
+	for name, opt := range map[string]func(*jrc.Server) error{"bare": jrc.BareSingle(true), "1.0": jrc.Version("1.0")} {
+		//the probe follows options given after HealthChecks too
+		srv, err := jrc.NewServer(ts.URL, jrc.HealthChecks(jrc.HealthCheck{Method: "status", Interval: time.Hour}), opt)
+		if err != nil {
+			t.Fatal(err)
+		}
+		deadline := time.Now().Add(time.Second)
+		for srv.EndpointStates()[0].LastCheck.IsZero() && time.Now().Before(deadline) {
+			time.Sleep(5 * time.Millisecond)
+		}
+		if s := srv.EndpointStates()[0]; !s.Healthy || s.LastCheck.IsZero() {
+			t.Errorf("%s: endpoint %+v", name, s)
+		}
+		srv.Close()
+	}
+}
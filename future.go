@@ -0,0 +1,38 @@
+package jrc
+
+import "context"
+
+//Future is a call started by Go, to be collected with Result once it is Done
+type Future struct {
+	Request RpcRequest
+	done    chan struct{}
+	resp    *RpcResponse
+	err     error
+}
+
+//Done returns a channel closed when the call has finished
+func (f *Future) Done() <-chan struct{} {
+	return f.done
+}
+
+//Result waits for the call to finish and returns what Exec would have
+func (f *Future) Result() (*RpcResponse, error) {
+	<-f.done
+	return f.resp, f.err
+}
+
+//Go starts a single call in the background and returns without waiting for the response
+//  many calls made this way share the Server's workers like those of a batch, but each one is sent on its own
+func (srv *Server) Go(r RpcRequest, opts ...CallOption) *Future {
+	return srv.GoContext(context.Background(), r, opts...)
+}
+
+//GoContext is Go with a context to cancel the call or apply a deadline
+func (srv *Server) GoContext(ctx context.Context, r RpcRequest, opts ...CallOption) *Future {
+	f := &Future{Request: r, done: make(chan struct{})}
+	go func() {
+		f.resp, f.err = srv.ExecContext(ctx, r, opts...)
+		close(f.done)
+	}()
+	return f
+}
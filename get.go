@@ -0,0 +1,100 @@
+package jrc
+
+import (
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+//GETEncoding selects how ReadOverGET puts a request body in the URL
+type GETEncoding int
+
+const (
+	//GETBase64 encodes the body as unpadded URL-safe base64
+	GETBase64 GETEncoding = iota
+	//GETURLEncoded puts the JSON of the body in the query as it is, percent-encoded
+	GETURLEncoded
+)
+
+func (srv *Server) setGET(param string, enc GETEncoding, methods []string) error {
+	if enc < GETBase64 || enc > GETURLEncoded {
+		return errors.New("jrc: unknown GET encoding")
+	}
+	if param == "" {
+		return errors.New("jrc: GET query parameter cannot be empty")
+	}
+	srv.getParam, srv.getEnc = param, enc
+	if srv.getMethods == nil {
+		srv.getMethods = make(map[string]bool, len(methods))
+	}
+	for _, m := range methods {
+		srv.getMethods[m] = true
+	}
+	return nil
+}
+
+//ReadOverGET sends calls holding only the given methods as HTTP GETs, the body encoded into the query parameter param,
+//  for gateways that take them so CDNs and proxies can cache hot read calls; a batch is split wherever a request
+//  of these methods follows one of others or the other way round, and the rest are posted as before
+//  the request ids are part of the URL, so calls meant to be cached need the same ids each time, and MaxBatch
+//  keeps the URLs of batches short enough for the servers and proxies on the way; transports without HTTP send the body
+//  SigV4 and DigestAuth sign such calls as the GETs they are, query included, and Dump shows them that way
+func ReadOverGET(param string, enc GETEncoding, methods ...string) func(server *Server) error {
+	return func(srv *Server) error {
+		return srv.setGET(param, enc, methods)
+	}
+}
+
+//subGETs reports for each of subs whether it is sent as a GET, nil when the Server has no ReadOverGET methods
+func (srv *Server) subGETs(subs []SubBatch) []bool {
+	if len(srv.getMethods) == 0 {
+		return nil
+	}
+	gets := make([]bool, len(subs))
+	for i, sb := range subs {
+		gets[i] = srv.getMethods[sb.Requests[0].Method]
+	}
+	return gets
+}
+
+//getQuery returns the query carrying body for ReadOverGET
+func (srv *Server) getQuery(body []byte) url.Values {
+	if srv.getEnc == GETURLEncoded {
+		return url.Values{srv.getParam: {string(body)}}
+	}
+	return url.Values{srv.getParam: {base64.RawURLEncoding.EncodeToString(body)}}
+}
+
+//method is the HTTP method m goes out with, GET for ReadOverGET calls
+func (m *Message) method() string {
+	if m.Query != nil {
+		return http.MethodGet
+	}
+	return http.MethodPost
+}
+
+//target is the URL m goes out to, the ReadOverGET query included
+func (m *Message) target() string {
+	if m.Query != nil {
+		return withQuery(m.URL, m.Query)
+	}
+	return m.URL
+}
+
+//payload is the body m goes out with, nil for ReadOverGET calls which carry it in the query
+func (m *Message) payload() []byte {
+	if m.Query != nil {
+		return nil
+	}
+	return m.Body
+}
+
+//withQuery adds q to the query of the URL u
+func withQuery(u string, q url.Values) string {
+	if strings.Contains(u, "?") {
+		return u + "&" + q.Encode()
+	}
+	return u + "?" + q.Encode()
+}
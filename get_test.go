@@ -0,0 +1,68 @@
+package jrc_test
+
+import (
+	"encoding/base64"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/cfoxon/jrc"
+)
+
+func TestReadOverGET(t *testing.T) {
+	type seen struct{ method, query, ct string }
+	var mu sync.Mutex
+	var calls []seen
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		if r.Method == http.MethodGet {
+			b, _ = base64.RawURLEncoding.DecodeString(r.URL.Query().Get("q"))
+		}
+		mu.Lock()
+		calls = append(calls, seen{r.Method, r.URL.RawQuery, r.Header.Get("Content-Type")})
+		mu.Unlock()
+		w.Write([]byte(`{"jsonrpc":"2.0","id":1,"result":` + string(b[len(b)-3]) + `}`))
+	}))
+	defer ts.Close()
+	for _, tr := range []jrc.Transport{nil, &jrc.NetHTTPTransport{}} {
+		calls = nil
+		opts := []func(*jrc.Server) error{jrc.ReadOverGET("q", jrc.GETBase64, "get_block"), jrc.BareSingle(true)}
+		if tr != nil {
+			opts = append(opts, jrc.UseTransport(tr))
+		}
+		srv, err := jrc.NewServer(ts.URL+"/rpc?key=x", opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		//the params end in the digit the fake server answers with
+		resps, err := srv.ExecBatch(jrc.RPCRequests{
+			{JsonRpc: "2.0", Id: 1, Method: "get_block", Params: []int{1}},
+			{JsonRpc: "2.0", Id: 2, Method: "broadcast", Params: []int{2}},
+		}, jrc.WithMaxBatch(1))
+		srv.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(resps) != 2 || string(resps[0].Result) != "1" || string(resps[1].Result) != "2" {
+			t.Errorf("responses %+v", resps)
+		}
+		if len(calls) != 2 {
+			t.Fatalf("calls %+v", calls)
+		}
+		get, post := calls[0], calls[1]
+		if get.method != http.MethodGet {
+			get, post = post, get
+		}
+		if get.method != http.MethodGet || get.ct != "" || get.query[:6] != "key=x&" {
+			t.Errorf("read call %+v", get)
+		}
+		if post.method != http.MethodPost || post.query != "key=x" {
+			t.Errorf("write call %+v", post)
+		}
+	}
+	if _, err := jrc.NewServer(ts.URL, jrc.ReadOverGET("", jrc.GETBase64, "get_block")); err == nil {
+		t.Error("an empty query parameter was accepted")
+	}
+}
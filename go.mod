@@ -3,12 +3,15 @@ module github.com/cfoxon/jrc
 go 1.18
 
 require (
+	github.com/andybalholm/brotli v1.0.4
 	github.com/goccy/go-json v0.10.0
+	github.com/klauspost/compress v1.15.9
 	github.com/valyala/fasthttp v1.43.0
+	golang.org/x/net v0.0.0-20220906165146-f3363e06e74c
+	golang.org/x/oauth2 v0.20.0
 )
 
 require (
-	github.com/andybalholm/brotli v1.0.4 // indirect
-	github.com/klauspost/compress v1.15.9 // indirect
 	github.com/valyala/bytebufferpool v1.0.0 // indirect
+	golang.org/x/text v0.3.7 // indirect
 )
@@ -0,0 +1,69 @@
+package jrc
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+)
+
+//header is a single HTTP header to set on outgoing requests
+type header struct {
+	key   string
+	value string
+}
+
+func (srv *Server) addHeader(key, value string) error {
+	srv.headers = append(srv.headers, header{key: key, value: value})
+	return nil
+}
+
+//Header adds a header to every request sent to the Server, replacing any default header with the same key
+func Header(key, value string) func(server *Server) error {
+	return func(srv *Server) error {
+		return srv.addHeader(key, value)
+	}
+}
+
+//setHeaders sets hs on dst in order, so later headers replace earlier ones with the same key
+func setHeaders(dst http.Header, hs []header) {
+	for _, h := range hs {
+		dst.Set(h.key, h.value)
+	}
+}
+
+//UserAgent sets the User-Agent sent with every request in place of the transport's default, which some gateways block or rate limit
+//  it is the same as Header("User-Agent", s)
+func UserAgent(s string) func(server *Server) error {
+	return func(srv *Server) error {
+		return srv.addHeader("User-Agent", s)
+	}
+}
+
+//ContentType sets the Content-Type of every request in place of the codec's, for servers that insist on one exactly,
+//  such as application/json-rpc; charset=utf-8; it is the same as Header("Content-Type", ct)
+func ContentType(ct string) func(server *Server) error {
+	return func(srv *Server) error {
+		if ct == "" {
+			return errors.New("jrc: content type cannot be empty")
+		}
+		return srv.addHeader("Content-Type", ct)
+	}
+}
+
+//AcceptEncoding sets the response encodings asked for in place of gzip, br and zstd, from those and identity
+//  with none only identity is asked for, for servers or proxies that mangle compressed replies
+func AcceptEncoding(encodings ...string) func(server *Server) error {
+	return func(srv *Server) error {
+		for _, enc := range encodings {
+			switch enc {
+			case "gzip", "br", "zstd", "identity":
+			default:
+				return errors.New("jrc: unsupported accept encoding " + enc)
+			}
+		}
+		if len(encodings) == 0 {
+			encodings = []string{"identity"}
+		}
+		return srv.addHeader("Accept-Encoding", strings.Join(encodings, ", "))
+	}
+}
@@ -0,0 +1,161 @@
+package jrc
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+//HealthCheck configures background probes of every endpoint
+//  an endpoint whose probe fails is skipped by failover and balancing until a later probe succeeds
+type HealthCheck struct {
+	Method   string        //a cheap RPC method to call, e.g. a status or version method
+	Params   interface{}   //params for Method, if any
+	Interval time.Duration //time between rounds of probes
+	Timeout  time.Duration //limit on each probe, Interval when zero
+}
+
+//EndpointState describes an endpoint of the Server as seen by failover, balancing and health checks
+type EndpointState struct {
+	URL         string
+	Healthy     bool          //true until a health check fails
+	CoolingDown bool          //a call failed within the FailoverCooldown
+	LastCheck   time.Time     //zero if no health check has run
+	LastError   error         //the error from the last health check, nil if it passed
+	Latency     time.Duration //average time of successful calls and probes
+}
+
+//EndpointStates reports the current state of each endpoint, in the order they were added or discovered
+func (srv *Server) EndpointStates() []EndpointState {
+	now := time.Now()
+	eps := srv.active()
+	states := make([]EndpointState, 0, len(eps))
+	for _, ep := range eps {
+		ep.mu.Lock()
+		states = append(states, EndpointState{
+			URL:         ep.url.String(),
+			Healthy:     ep.healthErr == nil,
+			CoolingDown: now.Before(ep.downUntil),
+			LastCheck:   ep.lastCheck,
+			LastError:   ep.healthErr,
+			Latency:     ep.latency,
+		})
+		ep.mu.Unlock()
+	}
+	return states
+}
+
+func (srv *Server) setHealthCheck(hc HealthCheck) error {
+	if hc.Method == "" {
+		return errors.New("jrc: health check needs a method")
+	}
+	if hc.Interval <= 0 {
+		return errors.New("jrc: health check interval must be positive")
+	}
+	if hc.Timeout <= 0 {
+		hc.Timeout = hc.Interval
+	}
+	if _, err := srv.probeBody(hc); err != nil {
+		return err
+	}
+	srv.health = &hc
+	return nil
+}
+
+//probeBody encodes the call of hc as a batch of one, in the Server's protocol version and shape
+//  it is built for every round, so a Server FallbackToSingle found refusing batches is probed with a bare object
+func (srv *Server) probeBody(hc HealthCheck) ([]byte, error) {
+	bodies, err := srv.marshalRequests([]SubBatch{{Requests: RPCRequests{{JsonRpc: "2.0", Id: 1, Method: hc.Method, Params: hc.Params}}}})
+	if err != nil {
+		return nil, err
+	}
+	return bodies[0], nil
+}
+
+//startHealthCheck (re)starts the background probes against the current endpoints once options are applied
+func (srv *Server) startHealthCheck() {
+	srv.stopHealthCheck()
+	srv.poolMu.Lock()
+	closed := srv.closed
+	srv.poolMu.Unlock()
+	if srv.health == nil || closed {
+		return
+	}
+	stop := make(chan struct{})
+	srv.healthStop = stop
+	eps := append([]*endpoint(nil), srv.endpoints...)
+	go srv.healthCheck(*srv.health, srv.tr, eps, srv.discovery, stop)
+}
+
+//stopHealthCheck ends the background probes, if they are running
+func (srv *Server) stopHealthCheck() {
+	if srv.healthStop != nil {
+		close(srv.healthStop)
+		srv.healthStop = nil
+	}
+}
+
+//HealthChecks probes every endpoint in the background until the Server is closed
+func HealthChecks(hc HealthCheck) func(server *Server) error {
+	return func(srv *Server) error {
+		return srv.setHealthCheck(hc)
+	}
+}
+
+func (srv *Server) healthCheck(hc HealthCheck, tr Transport, eps []*endpoint, d *discovery, stop chan struct{}) {
+	t := time.NewTicker(hc.Interval)
+	defer t.Stop()
+	for {
+		round := eps
+		if found := d.endpoints(); len(found) > 0 {
+			round = found
+		}
+		if body, err := srv.probeBody(hc); err != nil {
+			srv.log(LogError, "jrc: health check not encoded", "error", err)
+		} else {
+			for _, ep := range round {
+				srv.probe(ep, tr, hc.Timeout, body)
+			}
+		}
+		select {
+		case <-t.C:
+		case <-stop:
+			return
+		}
+	}
+}
+
+//probe calls the health check method on ep and records the outcome
+func (srv *Server) probe(ep *endpoint, t Transport, timeout time.Duration, body []byte) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	if t == nil {
+		t = ep.tr
+	}
+	hs, err := srv.callHeaders(ctx, &callConfig{})
+	start := time.Now()
+	var b []byte
+	if err == nil {
+		b, err = srv.deliver(ctx, t, &Message{URL: requestURL(ep.url, nil), Header: hs, Body: body, Jar: srv.jar})
+	}
+	if err == nil {
+		var resps []RpcResponse
+		resps, err = parseBatch(srv.codec, [][]byte{b}, nil)
+		if err == nil && len(resps) > 0 && resps[0].Error != nil {
+			err = resps[0].Error
+		}
+	}
+	if err == nil {
+		ep.observe(time.Since(start))
+	}
+	ep.mu.Lock()
+	was := ep.healthErr
+	ep.lastCheck = time.Now()
+	ep.healthErr = err
+	ep.mu.Unlock()
+	if err != nil {
+		srv.log(LogWarn, "jrc: health check failed", "url", ep.url.String(), "error", err)
+	} else if was != nil {
+		srv.log(LogInfo, "jrc: endpoint healthy again", "url", ep.url.String())
+	}
+}
@@ -0,0 +1,72 @@
+package jrc
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+type hedgeResult struct {
+	b   []byte
+	err error
+}
+
+//hedged sends c to eps in turn, starting the next one whenever the last has been waiting for srv.hedge or has failed
+//  the first success wins and the calls still in flight are cancelled
+func (srv *Server) hedged(c *call, eps []*endpoint) ([]byte, error) {
+	ctx, cancel := context.WithCancel(c.ctx)
+	defer cancel()
+	results := make(chan hedgeResult, len(eps))
+	var next, pending int
+	var wait <-chan time.Time
+	launch := func() {
+		m := *c.msg
+		m.Header = m.Header.Clone()
+		ep := eps[next]
+		next++
+		pending++
+		c.msg.tally.sent()
+		go func() {
+			b, err := srv.send(ctx, ep, &m)
+			results <- hedgeResult{b, err}
+		}()
+		wait = nil
+		if next < len(eps) {
+			wait = time.After(srv.hedge)
+		}
+	}
+	launch()
+	var last hedgeResult
+	for pending > 0 {
+		select {
+		case r := <-results:
+			pending--
+			if r.err == nil || c.ctx.Err() != nil {
+				return r.b, r.err
+			}
+			last = r
+			if next < len(eps) {
+				launch()
+			}
+		case <-wait:
+			launch()
+		}
+	}
+	return last.b, last.err
+}
+
+func (srv *Server) setHedge(after time.Duration) error {
+	if after < 0 {
+		return errors.New("jrc: hedge delay cannot be negative")
+	}
+	srv.hedge = after
+	return nil
+}
+
+//Hedge sends a call to the next endpoint as well if no response has come back after the given delay, taking whichever succeeds first
+//  meant for reads, as the server may see the call more than once; notifications are never hedged and zero turns hedging off
+func Hedge(after time.Duration) func(server *Server) error {
+	return func(srv *Server) error {
+		return srv.setHedge(after)
+	}
+}
@@ -0,0 +1,218 @@
+package jrc
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"sync"
+
+	"github.com/valyala/fasthttp"
+)
+
+//fasthttpTransport posts messages with a fasthttp.HostClient
+//  the primary endpoint uses the Server's client, others use a copy of its settings pointed at u
+type fasthttpTransport struct {
+	srv *Server
+	u   *url.URL
+
+	mu        sync.Mutex
+	hc        *fasthttp.HostClient
+	gen       int
+	redirects map[string]*fasthttp.HostClient //by scheme and host, for redirects away from the endpoint
+	redirGen  int
+}
+
+//client returns the HostClient to use, recloning it if the Server's options have changed since
+func (t *fasthttpTransport) client() *fasthttp.HostClient {
+	if t.u == nil {
+		return t.srv.hc
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.hc == nil || t.gen != t.srv.gen {
+		t.hc = cloneHostClient(t.srv.hc, t.srv.url, t.u)
+		t.gen = t.srv.gen
+	}
+	return t.hc
+}
+
+//Close drops the client's idle connections; it stays usable and will dial again if needed
+func (t *fasthttpTransport) Close() error {
+	if t.u == nil {
+		t.srv.hc.CloseIdleConnections()
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.hc != nil {
+		t.hc.CloseIdleConnections()
+	}
+	for _, hc := range t.redirects {
+		hc.CloseIdleConnections()
+	}
+	return nil
+}
+
+func (t *fasthttpTransport) RoundTrip(ctx context.Context, m *Message) ([]byte, error) {
+	req := fasthttp.AcquireRequest()
+	defer fasthttp.ReleaseRequest(req)
+	resp := fasthttp.AcquireResponse()
+	defer fasthttp.ReleaseResponse(resp)
+
+	req.SetRequestURI(m.URL)
+	addDefaultHeaders(req)
+	for k, vs := range m.Header {
+		req.Header.Del(k)
+		for _, v := range vs {
+			req.Header.Add(k, v)
+		}
+	}
+	if m.Query != nil {
+		req.SetRequestURI(m.target())
+		req.Header.SetMethod(m.method())
+		req.Header.Del("Content-Type")
+	} else {
+		req.SetBodyRaw(m.Body)
+	}
+	var u *url.URL
+	if m.Jar != nil {
+		var err error
+		if u, err = url.Parse(m.URL); err != nil {
+			return nil, err
+		}
+		addCookies(req, m.Jar, u)
+	}
+
+	err := do(ctx, t.client(), req, resp)
+	if err == nil && m.Redirects > 0 && isRedirect(resp.StatusCode()) {
+		err = t.followRedirects(ctx, req, resp, m.Redirects)
+	}
+	if err == fasthttp.ErrBodyTooLarge {
+		return nil, ErrResponseTooLarge
+	}
+	if err != nil {
+		return nil, err
+	}
+	if m.Jar != nil {
+		storeCookies(resp, m.Jar, u)
+	}
+	if m.Response != nil {
+		resp.Header.VisitAll(func(k, v []byte) {
+			m.Response.Add(string(k), string(v))
+		})
+	}
+	var b []byte
+	if contentEncoding := resp.Header.Peek("Content-Encoding"); len(contentEncoding) > 0 {
+		if b, err = decodeBody(string(contentEncoding), resp.Body(), m.MaxResponseSize); err != nil {
+			return nil, err
+		}
+	} else {
+		if m.MaxResponseSize > 0 && len(resp.Body()) > m.MaxResponseSize {
+			return nil, ErrResponseTooLarge
+		}
+		if m.Pooled {
+			b = append(getBuffer(len(resp.Body())), resp.Body()...)
+		} else {
+			b = make([]byte, len(resp.Body()))
+			copy(b, resp.Body())
+		}
+	}
+	if resp.StatusCode() == fasthttp.StatusUnauthorized && m.Challenge {
+		hs := make(http.Header)
+		resp.Header.VisitAll(func(k, v []byte) {
+			hs.Add(string(k), string(v))
+		})
+		return nil, &StatusError{StatusCode: resp.StatusCode(), Body: b, Header: hs}
+	}
+	if resp.StatusCode() >= fasthttp.StatusInternalServerError || resp.StatusCode() == fasthttp.StatusRequestEntityTooLarge ||
+		(m.Redirects > 0 && isRedirect(resp.StatusCode())) {
+		return nil, &StatusError{StatusCode: resp.StatusCode(), Body: b}
+	}
+	return b, nil
+}
+
+//do sends req with hc, by the deadline of ctx if it has one
+func do(ctx context.Context, hc *fasthttp.HostClient, req *fasthttp.Request, resp *fasthttp.Response) error {
+	if deadline, ok := ctx.Deadline(); ok {
+		return hc.DoDeadline(req, resp, deadline)
+	}
+	return hc.Do(req, resp)
+}
+
+//isRedirect reports whether status is one FollowRedirects follows
+func isRedirect(status int) bool {
+	switch status {
+	case fasthttp.StatusMovedPermanently, fasthttp.StatusFound, fasthttp.StatusTemporaryRedirect, fasthttp.StatusPermanentRedirect:
+		return true
+	}
+	return false
+}
+
+//followRedirects sends req again to the Location of each redirect resp holds, up to hops times,
+//  with the same method and body; Authorization and cookies are dropped once it leaves the endpoint's host
+func (t *fasthttpTransport) followRedirects(ctx context.Context, req *fasthttp.Request, resp *fasthttp.Response, hops int) error {
+	home, err := url.Parse(req.URI().String())
+	if err != nil {
+		return err
+	}
+	at := home
+	for ; hops > 0 && isRedirect(resp.StatusCode()); hops-- {
+		loc := resp.Header.Peek("Location")
+		if len(loc) == 0 {
+			return nil
+		}
+		next, err := at.Parse(string(loc))
+		if err != nil {
+			return err
+		}
+		if next.Scheme != "http" && next.Scheme != "https" {
+			return errors.New("jrc: redirect to unsupported url " + next.String())
+		}
+		next.Fragment, next.RawFragment = "", ""
+		hc := t.client()
+		if next.Scheme != home.Scheme || next.Host != home.Host {
+			req.Header.Del("Authorization")
+			req.Header.DelAllCookies()
+			hc = t.redirectClient(next)
+		}
+		req.SetRequestURI(next.String())
+		if err := do(ctx, hc, req, resp); err != nil {
+			return err
+		}
+		at = next
+	}
+	return nil
+}
+
+//redirectClient returns the HostClient for redirects to the host of u, with the settings of the Server's
+func (t *fasthttpTransport) redirectClient(u *url.URL) *fasthttp.HostClient {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.redirects == nil || t.redirGen != t.srv.gen {
+		t.redirects, t.redirGen = make(map[string]*fasthttp.HostClient), t.srv.gen
+	}
+	key := u.Scheme + "://" + u.Host
+	hc := t.redirects[key]
+	if hc == nil {
+		hc = cloneHostClient(t.srv.hc, t.srv.url, u)
+		t.redirects[key] = hc
+	}
+	return hc
+}
+
+func (srv *Server) setRedirects(n int) error {
+	if n < 0 {
+		return errors.New("jrc: redirect limit cannot be negative")
+	}
+	srv.redirects = n
+	return nil
+}
+
+//FollowRedirects follows up to n 301, 302, 307 and 308 redirects per HTTP call, as providers sending calls on
+//  to regional endpoints do, resending the same method and body to the new location; 0, the default, follows none
+//  and a redirect left after n hops fails the call with a *StatusError; a NetHTTPTransport leaves redirects to its Client
+func FollowRedirects(n int) func(server *Server) error {
+	return func(srv *Server) error {
+		return srv.setRedirects(n)
+	}
+}
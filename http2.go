@@ -0,0 +1,39 @@
+package jrc
+
+import (
+	"crypto/tls"
+	"net"
+	"net/http"
+
+	"golang.org/x/net/http2"
+)
+
+func (srv *Server) setHTTP2() error {
+	var rt http.RoundTripper
+	if srv.url.Scheme == "https" {
+		rt = &http.Transport{
+			Proxy:             http.ProxyFromEnvironment,
+			TLSClientConfig:   srv.hc.TLSConfig,
+			ForceAttemptHTTP2: true,
+		}
+	} else {
+		//cleartext HTTP/2 (h2c) with prior knowledge, there is no upgrade from HTTP/1.1
+		rt = &http2.Transport{
+			AllowHTTP: true,
+			DialTLS: func(network, addr string, _ *tls.Config) (net.Conn, error) {
+				return net.Dial(network, addr)
+			},
+		}
+	}
+	srv.tr = &NetHTTPTransport{Client: &http.Client{Transport: rt}}
+	return nil
+}
+
+//HTTP2 sends calls over HTTP/2 using the net/http backend, so concurrent batches share one multiplexed connection
+//  https servers that do not offer h2 fall back to HTTP/1.1, plain http servers must support h2c
+//  it must come after Address and TLSConfig, whose settings it copies
+func HTTP2() func(server *Server) error {
+	return func(srv *Server) error {
+		return srv.setHTTP2()
+	}
+}
@@ -0,0 +1,155 @@
+package jrc
+
+import (
+	"bytes"
+	"errors"
+	"strconv"
+
+	"github.com/cfoxon/jrc/internal/json"
+)
+
+type idKind uint8
+
+const (
+	idNull idKind = iota
+	idNumber
+	idString
+)
+
+//ID holds a JSON RPC 2.0 id, which may be a number, a string or null
+//  the zero value is null, and IDs are comparable so they can be used as map keys
+type ID struct {
+	kind idKind
+	num  int64
+	str  string
+}
+
+//IntID creates a numeric ID
+func IntID(n int) ID {
+	return ID{kind: idNumber, num: int64(n)}
+}
+
+//StringID creates a string ID
+func StringID(s string) ID {
+	return ID{kind: idString, str: s}
+}
+
+//Int returns the value of a numeric ID, ok is false for string and null IDs
+func (id ID) Int() (n int, ok bool) {
+	return int(id.num), id.kind == idNumber
+}
+
+//Str returns the value of a string ID, ok is false for numeric and null IDs
+func (id ID) Str() (s string, ok bool) {
+	return id.str, id.kind == idString
+}
+
+//IsNull reports whether the ID is null or was absent
+func (id ID) IsNull() bool {
+	return id.kind == idNull
+}
+
+//String formats the ID as it would appear in JSON, without quotes for string IDs
+func (id ID) String() string {
+	switch id.kind {
+	case idNumber:
+		return strconv.FormatInt(id.num, 10)
+	case idString:
+		return id.str
+	default:
+		return "null"
+	}
+}
+
+//MarshalJSON implements json.Marshaler
+func (id ID) MarshalJSON() ([]byte, error) {
+	switch id.kind {
+	case idNumber:
+		return strconv.AppendInt(nil, id.num, 10), nil
+	case idString:
+		return json.Marshal(id.str)
+	default:
+		return []byte("null"), nil
+	}
+}
+
+//UnmarshalJSON implements json.Unmarshaler
+func (id *ID) UnmarshalJSON(b []byte) error {
+	b = bytes.TrimSpace(b)
+	switch {
+	case len(b) == 0 || bytes.Equal(b, []byte("null")):
+		*id = ID{}
+	case b[0] == '"':
+		var s string
+		if err := json.Unmarshal(b, &s); err != nil {
+			return err
+		}
+		*id = StringID(s)
+	default:
+		n, err := strconv.ParseInt(string(b), 10, 64)
+		if err != nil {
+			//some servers echo integral ids as floats, e.g. 1.0 or 1e3
+			f, ferr := strconv.ParseFloat(string(b), 64)
+			if ferr != nil || f != float64(int64(f)) {
+				return errors.New("jrc: invalid id " + string(b))
+			}
+			n = int64(f)
+		}
+		*id = ID{kind: idNumber, num: n}
+	}
+	return nil
+}
+
+//ID returns the id r is sent with, StrId if it is set and Id otherwise
+func (r *RpcRequest) ID() ID {
+	if r.StrId != "" {
+		return StringID(r.StrId)
+	}
+	return IntID(r.Id)
+}
+
+//SetID sets Id or StrId from id, clearing the other; a null id clears both
+func (r *RpcRequest) SetID(id ID) {
+	r.Id, r.StrId = 0, ""
+	switch id.kind {
+	case idNumber:
+		r.Id = int(id.num)
+	case idString:
+		r.StrId = id.str
+	}
+}
+
+//hasID reports whether r was given an id, zero and empty being left for AutoID to fill in
+func (r *RpcRequest) hasID() bool {
+	return r.Id != 0 || r.StrId != ""
+}
+
+//rpcRequest is RpcRequest without its methods, for encoding it with a numeric id
+type rpcRequest RpcRequest
+
+//wireRequest is RpcRequest as sent, with whichever id it carries
+type wireRequest struct {
+	JsonRpc string      `json:"jsonrpc"`
+	Id      ID          `json:"id"`
+	Method  string      `json:"method"`
+	Params  interface{} `json:"params,omitempty"`
+}
+
+//MarshalJSON implements json.Marshaler, sending StrId as the id when it is set
+func (r RpcRequest) MarshalJSON() ([]byte, error) {
+	if r.StrId == "" {
+		return json.Marshal((*rpcRequest)(&r))
+	}
+	return json.Marshal(wireRequest{JsonRpc: r.JsonRpc, Id: StringID(r.StrId), Method: r.Method, Params: r.Params})
+}
+
+//UnmarshalJSON implements json.Unmarshaler, filling in StrId for a string id
+func (r *RpcRequest) UnmarshalJSON(b []byte) error {
+	var w wireRequest
+	if err := json.Unmarshal(b, &w); err != nil {
+		return err
+	}
+	*r = RpcRequest{JsonRpc: w.JsonRpc, Method: w.Method, Params: w.Params}
+	r.SetID(w.Id)
+	return nil
+}
@@ -0,0 +1,82 @@
+package jrc
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"math"
+	"sync/atomic"
+)
+
+//IDGenerator creates the ids given to requests by AutoID
+//  NextID is called from many goroutines and must be safe for concurrent use
+type IDGenerator interface {
+	NextID() ID
+}
+
+//IDGeneratorFunc adapts a function to an IDGenerator
+type IDGeneratorFunc func() ID
+
+//NextID calls f
+func (f IDGeneratorFunc) NextID() ID {
+	return f()
+}
+
+type sequentialIDs struct {
+	last uint64
+}
+
+func (g *sequentialIDs) NextID() ID {
+	for {
+		if n := atomic.AddUint64(&g.last, 1) % math.MaxInt64; n != 0 {
+			return ID{kind: idNumber, num: int64(n)}
+		}
+	}
+}
+
+//SequentialIDs counts up from 1, the default for AutoID
+func SequentialIDs() IDGenerator {
+	return &sequentialIDs{}
+}
+
+//RandomIDs picks random positive numbers below 2^53, so JavaScript servers read them exactly
+func RandomIDs() IDGenerator {
+	return IDGeneratorFunc(func() ID {
+		var b [8]byte
+		rand.Read(b[:])
+		return ID{kind: idNumber, num: int64(binary.BigEndian.Uint64(b[:])>>11) + 1}
+	})
+}
+
+//UUIDIDs gives string ids holding random (version 4) UUIDs
+func UUIDIDs() IDGenerator {
+	return IDGeneratorFunc(func() ID {
+		var u [16]byte
+		rand.Read(u[:])
+		u[6] = u[6]&0x0f | 0x40
+		u[8] = u[8]&0x3f | 0x80
+		var s [36]byte
+		hex.Encode(s[0:8], u[0:4])
+		s[8] = '-'
+		hex.Encode(s[9:13], u[4:6])
+		s[13] = '-'
+		hex.Encode(s[14:18], u[6:8])
+		s[18] = '-'
+		hex.Encode(s[19:23], u[8:10])
+		s[23] = '-'
+		hex.Encode(s[24:], u[10:])
+		return StringID(string(s[:]))
+	})
+}
+
+//GenerateIDs turns on AutoID with ids from g
+func GenerateIDs(g IDGenerator) func(server *Server) error {
+	return func(srv *Server) error {
+		if g == nil {
+			g = SequentialIDs()
+		}
+		srv.ids = g
+		srv.autoID = true
+		return nil
+	}
+}
@@ -0,0 +1,20 @@
+//go:build !jrc_stdjson
+
+//Package json is the JSON package jrc uses, goccy/go-json unless built with the jrc_stdjson tag
+package json
+
+import "github.com/goccy/go-json"
+
+type (
+	RawMessage  = json.RawMessage
+	Marshaler   = json.Marshaler
+	Unmarshaler = json.Unmarshaler
+)
+
+func Marshal(v interface{}) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func Unmarshal(data []byte, v interface{}) error {
+	return json.Unmarshal(data, v)
+}
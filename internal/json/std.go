@@ -0,0 +1,20 @@
+//go:build jrc_stdjson
+
+//Package json is the JSON package jrc uses, encoding/json as built with the jrc_stdjson tag
+package json
+
+import "encoding/json"
+
+type (
+	RawMessage  = json.RawMessage
+	Marshaler   = json.Marshaler
+	Unmarshaler = json.Unmarshaler
+)
+
+func Marshal(v interface{}) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func Unmarshal(data []byte, v interface{}) error {
+	return json.Unmarshal(data, v)
+}
@@ -2,11 +2,18 @@ package jrc
 
 import (
 	"bytes"
+	"context"
 	"errors"
+	"io"
+	"net/http"
 	"net/url"
+	"sort"
+	"strconv"
 	"sync"
+	"sync/atomic"
+	"time"
 
-	"github.com/goccy/go-json"
+	"github.com/cfoxon/jrc/internal/json"
 	"github.com/valyala/fasthttp"
 )
 
@@ -18,6 +25,7 @@ type RpcRequest struct {
 	Id      int         `json:"id"`
 	Method  string      `json:"method"`
 	Params  interface{} `json:"params,omitempty"`
+	StrId   string      `json:"-"` //sent as the id in place of Id when set, for servers wanting string ids
 }
 
 //RpcResponse contains an RPC response with the Result field left un-decoded
@@ -25,7 +33,7 @@ type RpcResponse struct {
 	JSONRPC string          `json:"jsonrpc"`
 	Result  json.RawMessage `json:"result,omitempty"`
 	Error   *RpcError       `json:"error,omitempty"`
-	ID      int             `json:"id"`
+	ID      ID              `json:"id"`
 }
 
 //RpcError holds decoded RPC errors
@@ -35,19 +43,100 @@ type RpcError struct {
 	Data    interface{} `json:"data,omitempty"`
 }
 
+//Error implements the error interface so an RpcError can be returned as a Go error
+func (e *RpcError) Error() string {
+	return "jrc: rpc error " + strconv.Itoa(e.Code) + ": " + e.Message
+}
+
 //Server contains information related to connecting to an RPC server
 type Server struct {
-	url   *url.URL
-	hc    *fasthttp.HostClient
-	conn  int
-	batch int
-	reqc  chan *fasthttp.Request
-	resc  chan []byte
-	wg    *sync.WaitGroup
+	url        *url.URL
+	hc         *fasthttp.HostClient
+	tr         Transport //replaces the endpoints' own transports when set
+	endpoints  []*endpoint
+	profiles   map[string]*profile //by method, from Profile
+	getMethods map[string]bool     //from ReadOverGET
+	getParam   string
+	getEnc     GETEncoding
+	discovery  *discovery //replaces endpoints with those it finds when set
+	cooldown   time.Duration
+	hedge      time.Duration
+	balance    Balancing
+	next       uint32 //round robin position
+	gen        int    //bumped by SetOption so clients cloned from hc are rebuilt
+	conn       int
+	batch      int
+	adapt      *adaptiveBatch
+	retry      RetryPolicy
+	breaker    *breaker
+	limit      *limiter
+	metrics    Metrics
+	tracer     Tracer
+	logger     Logger
+	verbosity  LogLevel
+	middleware []Middleware
+	handler    Handler
+	dump       *dumper
+	order      bool
+	strict     bool
+	autoID     bool
+	idCheck    IDCheck
+	bare       bool   //from BareSingle
+	redirects  int    //from FollowRedirects
+	fallback   bool   //from FallbackToSingle
+	unbatched  uint32 //set atomically once FallbackToSingle has seen a batch refused
+	v1         bool
+	ids        IDGenerator
+	codec      Codec
+	headers    []header
+	gzipMin    int
+	maxResp    int
+	dialLimit  time.Duration //from DialTimeout, set when it has replaced hc.Dial
+	pool       bool
+	jar        http.CookieJar
+	sign       func(ctx context.Context, m *Message) error //SigV4, once the endpoint is chosen
+	digest     *digestAuth
+	cache      *resultCache
+	flights    *flightGroup
+	auth       func(ctx context.Context) (string, error)
+	health     *HealthCheck
+	healthStop chan struct{} //closed to stop the HealthChecks goroutine
+	events     eventStreams
+	reqc       chan *call
+	shrink     chan struct{} //takes one worker out of the pool
+	quit       chan struct{} //closed to stop the pool
+	workers    int
+	closed     bool
+	poolMu     sync.Mutex
+	poolWg     sync.WaitGroup
+}
+
+//call is a single message for the Server along with where its response should be delivered
+type call struct {
+	ctx   context.Context
+	msg   *Message
+	body  []byte //before compression
+	i     int    //index of the body among those of its batch
+	resc  chan response
+	taken uint32 //set atomically by the worker that took the call
+}
+
+//response is what a worker sends back for a call
+type response struct {
+	i   int
+	b   []byte //nil for a notification
+	err error
+
+	latency time.Duration //of the call in the worker, for WithStats
 }
 
 //SetOption changes server configuration with options
 func (srv *Server) SetOption(options ...func(*Server) error) error {
+	defer func() {
+		srv.gen++
+		srv.buildHandler()
+		srv.startHealthCheck()
+	}()
 	for _, opt := range options {
 		if err := opt(srv); err != nil {
 			return err
@@ -61,11 +150,33 @@ func (srv *Server) setAddress(s string) error {
 	if err != nil {
 		return err
 	}
-	srv.url = u
+	srv.setURL(u)
 	return nil
 }
 
+//setURL points the Server's primary endpoint at u
+func (srv *Server) setURL(u *url.URL) {
+	if srv.url != nil && srv.url.Scheme == "unix" {
+		srv.hc.Dial = nil
+	}
+	if u.Scheme == "unix" {
+		srv.dialLimit = 0
+	}
+	srv.url = u
+	configureHostClient(srv.hc, u)
+	ep := srv.newEndpoint(u, true)
+	if len(srv.endpoints) == 0 {
+		srv.endpoints = []*endpoint{ep}
+		return
+	}
+	srv.endpoints[0].close()
+	srv.endpoints[0] = ep
+}
+
 func (srv *Server) setMaxCon(n int) error {
+	if n < 1 {
+		return errors.New("jrc: MaxCon must be at least 1")
+	}
 	srv.conn = n
 	return nil
 }
@@ -77,121 +188,468 @@ func (srv *Server) setMaxBatch(n int) error {
 
 //ExecBatch executes a batch of calls and parses the JSON RPC 2.0 portion of the body
 //  the Result field is left as json.RawMessage for further parsing by the caller
-func (srv *Server) ExecBatch(rs RPCRequests) ([]RpcResponse, error) {
-	bs, err := srv.ExecBatchFast(rs)
-	if err != nil {
+//  if some HTTP calls fail, the responses from the others are returned with a *BatchError
+//  a batch small enough for one HTTP call returns the error of that call instead, such as a *TransportError
+//  a sub-batch answered with a single error object with a null id, as servers refusing the batch itself send, gives that error to each of its requests
+func (srv *Server) ExecBatch(rs RPCRequests, opts ...CallOption) ([]RpcResponse, error) {
+	return srv.ExecBatchContext(context.Background(), rs, opts...)
+}
+
+//ExecBatchContext is ExecBatch with a context to cancel the batch or apply a deadline
+//  if the context is done mid-batch, the responses that arrived are returned with ctx.Err()
+func (srv *Server) ExecBatchContext(ctx context.Context, rs RPCRequests, opts ...CallOption) ([]RpcResponse, error) {
+	sent := rs
+	var hits []RpcResponse
+	var keys map[*RpcRequest]string
+	if srv.cache != nil {
+		if err := srv.assignIDs(rs); err != nil {
+			return nil, err
+		}
+		if hits, sent, keys = srv.cache.lookup(rs); len(sent) == 0 {
+			if srv.order {
+				hits = ordered(rs, hits)
+			}
+			return hits, nil
+		}
+	}
+	wasUnbatched := srv.isUnbatched()
+	bs, subs, err := srv.execBatchFast(ctx, sent, opts)
+	if err != nil && !partial(ctx, err) {
 		return nil, err
 	}
-	resps, err := parseBatch(bs)
+	v := srv.validator(sent)
+	var resps []RpcResponse
+	var perr error
+	var again RPCRequests
+	for i, b := range bs {
+		if b == nil {
+			//the calls that failed or were never made have no body
+			continue
+		}
+		var r []RpcResponse
+		r, perr = parseBatch(srv.codec, [][]byte{b}, v)
+		if srv.refused(subs[i].Requests, r, perr, wasUnbatched) {
+			again, perr = append(again, subs[i].Requests...), nil
+			continue
+		}
+		if perr != nil {
+			break
+		}
+		resps = append(resps, spread(r, subs[i].Requests)...)
+	}
+	srv.Release(bs)
+	if perr != nil {
+		return nil, perr
+	}
+	if len(again) > 0 {
+		//sent one at a time now that the Server is unbatched
+		more, aerr := srv.ExecBatchContext(ctx, again, opts...)
+		if aerr != nil && !partial(ctx, aerr) {
+			return nil, aerr
+		}
+		if err == nil {
+			err = aerr
+		}
+		resps = append(resps, more...)
+	}
+	if srv.cache != nil {
+		srv.cache.store(sent, resps, keys)
+		resps = append(hits, resps...)
+	}
+	if srv.order {
+		resps = ordered(rs, resps)
+	}
+	return resps, err
+}
+
+//ExecBatchStream executes a batch of calls, passing each response to fn as soon as the HTTP call carrying it returns
+//  responses come in the order the calls complete, whatever PreserveOrder is set to; an error from fn stops the batch and is returned
+//  if some HTTP calls fail the rest carry on, and a *BatchError is returned at the end
+func (srv *Server) ExecBatchStream(rs RPCRequests, fn func(RpcResponse) error, opts ...CallOption) error {
+	return srv.ExecBatchStreamContext(context.Background(), rs, fn, opts...)
+}
+
+//ExecBatchStreamContext is ExecBatchStream with a context to cancel the batch or apply a deadline
+func (srv *Server) ExecBatchStreamContext(ctx context.Context, rs RPCRequests, fn func(RpcResponse) error, opts ...CallOption) error {
+	if len(rs) < 1 {
+		return nil
+	}
+	if err := srv.assignIDs(rs); err != nil {
+		return err
+	}
+	cfg := newCallConfig(opts)
+	wasUnbatched := srv.isUnbatched()
+	subs := srv.split(rs, cfg.batchSize(srv))
+	bodies, err := srv.marshalRequests(subs)
 	if err != nil {
-		return nil, err
+		return err
 	}
-	return resps, nil
+	cfg.sizes = subSizes(subs)
+	cfg.profiles = srv.subProfiles(subs)
+	cfg.gets = srv.subGETs(subs)
+	v := srv.validator(rs)
+	var again RPCRequests
+	err = srv.stream(ctx, bodies, cfg, func(i int, b []byte) error {
+		resps, err := parseBatch(srv.codec, [][]byte{b}, v)
+		srv.Release([][]byte{b})
+		if srv.refused(subs[i].Requests, resps, err, wasUnbatched) {
+			again = append(again, subs[i].Requests...)
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		for _, resp := range spread(resps, subs[i].Requests) {
+			if err := fn(resp); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	attribute(err, subs)
+	if _, failed := err.(*BatchError); len(again) > 0 && (err == nil || failed) {
+		//sent one at a time now that the Server is unbatched
+		if aerr := srv.ExecBatchStreamContext(ctx, again, fn, opts...); err == nil {
+			err = aerr
+		}
+	}
+	return err
 }
 
 //Exec executes a single remote procedure call
-func (srv *Server) Exec(r RpcRequest) (*RpcResponse, error) {
-	resps, err := srv.ExecBatch(RPCRequests{&r})
+func (srv *Server) Exec(r RpcRequest, opts ...CallOption) (*RpcResponse, error) {
+	return srv.ExecContext(context.Background(), r, opts...)
+}
+
+//ExecContext is Exec with a context to cancel the call or apply a deadline
+func (srv *Server) ExecContext(ctx context.Context, r RpcRequest, opts ...CallOption) (*RpcResponse, error) {
+	if srv.flights != nil {
+		return srv.flights.do(ctx, r, func() (*RpcResponse, error) {
+			return srv.exec(ctx, r, opts)
+		})
+	}
+	return srv.exec(ctx, r, opts)
+}
+
+//exec makes the single call r
+func (srv *Server) exec(ctx context.Context, r RpcRequest, opts []CallOption) (*RpcResponse, error) {
+	resps, err := srv.ExecBatchContext(ctx, RPCRequests{&r}, opts...)
 	if err != nil {
 		return nil, err
 	}
+	if len(resps) == 0 {
+		//the server answered with an empty batch
+		return nil, &ParseError{Err: errors.New("jrc: no response to the call")}
+	}
 	return &resps[0], nil
 }
 
-//startClients starts n background tasks to make requests to the Server
-func (srv *Server) startClients(n int) {
-	for i := 0; i < n; i++ {
-		go srv.client()
-	}
+//ExecBatchFast returns a slice of []byte containing the responses to the remote procedure calls
+//  there is one body for each sub-batch given by Split, in the same order
+//  if some HTTP calls fail, a *BatchError is returned and their bodies are nil
+func (srv *Server) ExecBatchFast(rs RPCRequests, opts ...CallOption) ([][]byte, error) {
+	return srv.ExecBatchFastContext(context.Background(), rs, opts...)
 }
 
-//ExecBatchFast returns a slice of []byte containing the responses to the remote procedure calls
-func (srv *Server) ExecBatchFast(rs RPCRequests) ([][]byte, error) {
+//ExecBatchFastContext is ExecBatchFast with a context to cancel the batch or apply a deadline
+//  if the context is done before all responses arrive, the calls not yet made are dropped and ctx.Err()
+//  is returned along with the bodies that did arrive, nil for the rest
+func (srv *Server) ExecBatchFastContext(ctx context.Context, rs RPCRequests, opts ...CallOption) ([][]byte, error) {
+	bs, _, err := srv.execBatchFast(ctx, rs, opts)
+	return bs, err
+}
+
+//execBatchFast is ExecBatchFastContext, also returning the sub-batch each body answers
+func (srv *Server) execBatchFast(ctx context.Context, rs RPCRequests, opts []CallOption) ([][]byte, []SubBatch, error) {
 	if rs == nil || len(rs) < 1 {
-		return nil, nil
+		return nil, nil, nil
+	}
+	if err := srv.assignIDs(rs); err != nil {
+		return nil, nil, err
 	}
-	maxConn := srv.conn
-	if maxConn > len(rs) {
-		maxConn = len(rs)
+	cfg := newCallConfig(opts)
+	subs := srv.split(rs, cfg.batchSize(srv))
+	bodies, err := srv.marshalRequests(subs)
+	if err != nil {
+		return nil, nil, err
 	}
-	srv.startClients(maxConn)
-	rc := make(chan [][]byte)
-	go srv.responses(rc)
+	cfg.sizes = subSizes(subs)
+	cfg.profiles = srv.subProfiles(subs)
+	cfg.gets = srv.subGETs(subs)
+	res, err := srv.post(ctx, bodies, cfg)
+	attribute(err, subs)
+	return res, subs, err
+}
 
-	uri := fasthttp.AcquireURI()
+//marshalBatches splits items into batches of at most size and encodes each into a request body with c
+//  a size less than 1 puts all of items in one body
+func marshalBatches[T any](c Codec, items []T, size int) ([][]byte, error) {
+	if size < 1 {
+		size = len(items)
+	}
+	var bodies [][]byte
+	for start := 0; start < len(items); start += size {
+		end := start + size
+		if end > len(items) {
+			end = len(items)
+		}
+		b, err := c.Marshal(items[start:end])
+		if err != nil {
+			return nil, err
+		}
+		bodies = append(bodies, b)
+	}
+	return bodies, nil
+}
 
-	if err := uri.Parse(nil, []byte(srv.url.String())); err != nil {
+//post sends each body to the Server as its own call and returns the response bodies in the same order
+//  with a *BatchError or ctx.Err(), the bodies of the calls that succeeded are still returned
+func (srv *Server) post(ctx context.Context, bodies [][]byte, cfg *callConfig) ([][]byte, error) {
+	if cfg.notify {
+		return nil, srv.stream(ctx, bodies, cfg, func(_ int, b []byte) error {
+			srv.Release([][]byte{b})
+			return nil
+		})
+	}
+	res := make([][]byte, len(bodies))
+	err := srv.stream(ctx, bodies, cfg, func(i int, b []byte) error {
+		res[i] = b
+		return nil
+	})
+	if err != nil && !partial(ctx, err) {
 		return nil, err
 	}
-	var batch RPCRequests
-	for _, rrc := range rs {
-		batch = append(batch, rrc)
-		if len(batch) == srv.batch {
-			req := fasthttp.AcquireRequest()
-			req.SetURI(uri)
-			addDefaultHeaders(req)
+	return res, err
+}
 
-			b, _ := json.Marshal(batch)
-			req.SetBodyRaw(b)
+//partial reports whether err from a batch still comes with the responses that arrived,
+//  as it does for a *BatchError or the batch's context being done
+func partial(ctx context.Context, err error) bool {
+	if _, ok := err.(*BatchError); ok {
+		return true
+	}
+	return err != nil && err == ctx.Err()
+}
 
-			srv.wg.Add(1)
+//stream sends each body to the Server as its own call, passing each response body to sink along with its index as it arrives
+func (srv *Server) stream(ctx context.Context, bodies [][]byte, cfg *callConfig, sink func(int, []byte) error) error {
+	if srv.tracer == nil {
+		return srv.dispatch(ctx, bodies, cfg, sink)
+	}
+	ctx, end := srv.tracer.StartBatch(ctx, len(bodies))
+	err := srv.dispatch(ctx, bodies, cfg, sink)
+	end(err)
+	return err
+}
+
+//dispatch hands the bodies to the workers and feeds their responses to sink, stopping the calls in flight if sink fails
+//  calls that fail are collected into a *BatchError returned once the others are done, or when the batch
+//  is a single call its error is returned as is
+func (srv *Server) dispatch(ctx context.Context, bodies [][]byte, cfg *callConfig, sink func(int, []byte) error) error {
+	srv.log(LogDebug, "jrc: dispatching", "calls", len(bodies), "notify", cfg.notify)
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	quit, err := srv.startWorkers()
+	if err != nil {
+		return err
+	}
+	if extra := cfg.conn - srv.conn; extra > 0 && len(bodies) > srv.conn {
+		stop, err := srv.extraWorkers(extra)
+		if err != nil {
+			return err
+		}
+		defer stop()
+	}
+	var path *url.URL
+	if cfg.path != "" {
+		if path, err = url.Parse(cfg.path); err != nil {
+			return err
+		}
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
 
-			srv.reqc <- req
-			batch = nil
+	//resc is buffered for every body so workers never block on a caller that has gone away
+	resc := make(chan response, len(bodies))
+	var next *call
+	var failed []CallError
+	outstanding := make(map[int]*call)
+	sent, pending := 0, 0
+	prog := newProgress(cfg)
+	st := newStats(cfg, len(bodies))
+	defer st.end()
+	for (quit != nil && sent < len(bodies)) || pending > 0 {
+		var reqc chan *call
+		if quit != nil && sent < len(bodies) && (cfg.conn == 0 || pending < cfg.conn) {
+			if next == nil {
+				hs, err := srv.callHeaders(ctx, cfg)
+				if err != nil {
+					return err
+				}
+				m := &Message{Header: hs, Body: bodies[sent], Notify: cfg.notify, MaxResponseSize: srv.maxResp, Pooled: srv.pool, Jar: srv.jar, Redirects: srv.redirects, path: path}
+				if sent < len(cfg.sizes) {
+					m.Requests = cfg.sizes[sent]
+				}
+				if sent < len(cfg.profiles) {
+					m.profile = cfg.profiles[sent]
+				}
+				if sent < len(cfg.gets) && cfg.gets[sent] {
+					m.Query = srv.getQuery(bodies[sent])
+				} else {
+					srv.compress(m)
+				}
+				if st != nil {
+					m.tally = &tally{}
+				}
+				next = &call{ctx: ctx, i: sent, msg: m, body: bodies[sent], resc: resc}
+			}
+			reqc = srv.reqc
 		}
+		select {
+		case reqc <- next:
+			outstanding[next.i] = next
+			prog.sent(next.i, len(next.msg.Body))
+			st.sent(len(next.msg.Body))
+			next = nil
+			sent++
+			pending++
+		case res := <-resc:
+			if outstanding[res.i] == nil {
+				//given up on at Close
+				continue
+			}
+			st.done(res, outstanding[res.i].msg.tally)
+			delete(outstanding, res.i)
+			pending--
+			prog.done(res.i, res.b, res.err)
+			if res.err != nil {
+				failed = append(failed, CallError{SubBatch: SubBatch{Index: res.i}, Err: res.err})
+			} else if res.b != nil {
+				if err := sink(res.i, res.b); err != nil {
+					return err
+				}
+			}
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-quit:
+			//calls still queued will not be made, but those a worker has taken are waited for
+			quit = nil
+			for i := sent; i < len(bodies); i++ {
+				failed = append(failed, CallError{SubBatch: SubBatch{Index: i}, Err: ErrClosed})
+			}
+			for i, c := range outstanding {
+				if atomic.LoadUint32(&c.taken) == 0 {
+					delete(outstanding, i)
+					pending--
+					failed = append(failed, CallError{SubBatch: SubBatch{Index: i}, Err: ErrClosed})
+				}
+			}
+		}
+	}
+	if len(bodies) == 1 && len(failed) == 1 {
+		return failed[0].Err
+	}
+	if len(failed) > 0 {
+		sort.Slice(failed, func(a, b int) bool { return failed[a].Index < failed[b].Index })
+		return &BatchError{Calls: len(bodies), Failed: failed}
+	}
+	return nil
+}
+
+//callHeaders returns the headers for one HTTP call, fetching a fresh Authorization value if the Server has a token source
+func (srv *Server) callHeaders(ctx context.Context, cfg *callConfig) (http.Header, error) {
+	hs := make(http.Header, len(srv.headers)+len(cfg.headers)+1)
+	if ct := srv.codec.ContentType(); ct != jsonContentType {
+		hs.Set("Content-Type", ct)
 	}
-	if len(batch) > 0 {
-		req := fasthttp.AcquireRequest()
-		req.SetURI(uri)
-		addDefaultHeaders(req)
-		b, _ := json.Marshal(batch)
-		req.SetBodyRaw(b)
-		srv.wg.Add(1)
-		srv.reqc <- req
+	setHeaders(hs, srv.headers)
+	if srv.auth != nil {
+		v, err := srv.auth(ctx)
+		if err != nil {
+			return nil, err
+		}
+		hs.Set("Authorization", v)
 	}
-	fasthttp.ReleaseURI(uri)
-	srv.wg.Wait()
-	close(srv.resc)
-	res := <-rc
-	return res, nil
+	setHeaders(hs, cfg.headers)
+	return hs, nil
 }
 
-//client creates a background worker process which will monitor the requests channel for requests to make to the Server
-func (srv *Server) client() {
+//client is a worker in the Server's pool, making the calls sent on the requests channel until it is stopped
+//  shrink is nil for workers outside the pool, which only stop with quit
+func (srv *Server) client(quit, shrink chan struct{}) {
+	defer srv.poolWg.Done()
 	for {
-		req := <-srv.reqc
-		resp := fasthttp.AcquireResponse()
-		err := srv.hc.Do(req, resp)
-		fasthttp.ReleaseRequest(req)
+		var c *call
+		select {
+		case c = <-srv.reqc:
+			atomic.StoreUint32(&c.taken, 1)
+		case <-shrink:
+			return
+		case <-quit:
+			return
+		}
+		if err := done(c.ctx); err != nil {
+			//the batch has been given up on while the call was queued
+			c.resc <- response{i: c.i, err: err}
+			continue
+		}
+		start := time.Now()
+		ctx, cancel := c.msg.profile.context(c.ctx)
+		b, err := srv.handler(ctx, c.msg)
+		cancel()
+		if srv.metrics != nil {
+			srv.observe(c, b, err, time.Since(start))
+		}
 		if err != nil {
-			srv.resc <- []byte(err.Error())
-			srv.wg.Done()
-		} else {
-			var b []byte
-			contentEncoding := resp.Header.Peek("Content-Encoding")
-			if bytes.EqualFold(contentEncoding, []byte("gzip")) {
-				b, _ = resp.BodyGunzip()
-			} else {
-				b = make([]byte, len(resp.Body()))
-				copy(b, resp.Body())
-			}
-			
-			if b != nil {
-				srv.resc <- b
-			}
-			srv.wg.Done()
+			srv.log(LogError, "jrc: call failed", "error", err)
+			err = wrapTransport(err)
 		}
-		fasthttp.ReleaseResponse(resp)
+		c.resc <- response{i: c.i, b: b, err: err, latency: time.Since(start)}
 	}
 }
 
-//responses opens a channel to gather the incoming responses from the server
-func (srv *Server) responses(rc chan [][]byte) {
-	var bs [][]byte
-	for b := range srv.resc {
-		bs = append(bs, b)
+//done returns ctx.Err(), or context.DeadlineExceeded as soon as the deadline has passed, before ctx's own timer fires
+func done(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if d, ok := ctx.Deadline(); ok && !time.Now().Before(d) {
+		return context.DeadlineExceeded
+	}
+	return nil
+}
+
+//Close shuts the Server down, waiting for calls in flight before closing idle connections and stopping any child process
+//  calls in flight on tcp://, ipc://, ws:// and command connections fail instead, as the server may never answer them
+//  health checks and endpoint discovery stop, subscriptions end, and calls still queued or made after Close fail with ErrClosed; closing again does nothing
+func (srv *Server) Close() error {
+	srv.stopHealthCheck()
+	found := srv.stopDiscovery()
+	srv.events.close()
+	if !srv.stopWorkers() {
+		return nil
+	}
+	var err error
+	closeEndpoints := func(streams bool) {
+		for _, ep := range append(append([]*endpoint(nil), found...), srv.endpoints...) {
+			if (streamOf(ep.tr) != nil) != streams {
+				continue
+			}
+			if cerr := ep.close(); err == nil {
+				err = cerr
+			}
+		}
 	}
-	rc <- bs
+	closeEndpoints(true)
+	srv.poolWg.Wait()
+	if c, ok := srv.tr.(io.Closer); ok {
+		if cerr := c.Close(); err == nil {
+			err = cerr
+		}
+	}
+	closeEndpoints(false)
+	return err
 }
 
 //Address sets the url of the Server
@@ -208,7 +666,7 @@ func MaxCon(n int) func(server *Server) error {
 	}
 }
 
-//MaxBatch sets the maximum number of RPCs to batch into a single call
+//MaxBatch sets the maximum number of RPCs to batch into a single call, 0 for no limit
 func MaxBatch(n int) func(server *Server) error {
 	return func(srv *Server) error {
 		return srv.setMaxBatch(n)
@@ -216,6 +674,10 @@ func MaxBatch(n int) func(server *Server) error {
 }
 
 //NewServer creates a target for clients
+//  addr is an http or https url, or unix:///path/to.sock to speak HTTP over a unix socket
+//  the path and query of an http or https url are sent with every request as they are, only the fragment is dropped
+//  tcp://host:port and ipc:///path/to.sock speak JSON RPC directly over the connection, see StreamFraming
+//  ws:// and wss:// urls speak it over a WebSocket, which also carries subscriptions, see Subscribe
 func NewServer(addr string, options ...func(*Server) error) (*Server, error) {
 	srv, err := newDefaultServer(addr)
 	if err != nil {
@@ -232,29 +694,70 @@ func newDefaultServer(addr string) (*Server, error) {
 	if err != nil {
 		return nil, err
 	}
-	hc := &fasthttp.HostClient{Addr: u.Host, DialDualStack: true}
-	if u.Scheme == "https" {
-		hc.IsTLS = true
+	srv := &Server{
+		//the path is sent as given, as some gateways route on escapes such as %2F or on a trailing slash
+		hc:       &fasthttp.HostClient{DialDualStack: true, DisablePathNormalizing: true},
+		cooldown: 10 * time.Second,
+		conn:     4,
+		batch:    50,
+		reqc:     make(chan *call),
+		shrink:   make(chan struct{}),
+		ids:      SequentialIDs(),
+		codec:    JSONCodec{},
+	}
+	srv.setURL(u)
+	return srv, nil
+}
+
+//spread gives each of rs the error the Server answered their sub-batch with as a whole, as a single object with a null id,
+//  which is how some servers refuse a batch or report that it could not be parsed; other responses are returned as they are
+func spread(resps []RpcResponse, rs RPCRequests) []RpcResponse {
+	if len(resps) != 1 || !resps[0].ID.IsNull() || resps[0].Error == nil || len(rs) == 0 {
+		return resps
+	}
+	out := make([]RpcResponse, len(rs))
+	for i, r := range rs {
+		out[i] = resps[0]
+		out[i].ID = r.ID()
 	}
-	var wg sync.WaitGroup
-	return &Server{
-		url:   u,
-		hc:    hc,
-		conn:  4,
-		batch: 50,
-		reqc:  make(chan *fasthttp.Request),
-		resc:  make(chan []byte),
-		wg:    &wg,
-	}, nil
+	return out
 }
 
-func parseBatch(bs [][]byte) ([]RpcResponse, error) {
+//parseBatch decodes the responses in bs with c, checking them with v unless it is nil
+func parseBatch(c Codec, bs [][]byte, v *validator) ([]RpcResponse, error) {
 	var resps []RpcResponse
 	for _, b := range bs {
 		var r []RpcResponse
-		if err := json.Unmarshal(b, &r); err != nil {
-			errb := errors.New(err.Error() + "\n" + string(b))
-			return nil, errb
+		var err error
+		if !isJSON(c) {
+			if err = c.Unmarshal(b, &r); err != nil {
+				//a single object, as for a single request in 1.0 mode
+				r = make([]RpcResponse, 1)
+				if c.Unmarshal(b, &r[0]) == nil {
+					err = nil
+				}
+			}
+		} else if b = bytes.TrimSpace(b); len(b) > 0 && b[0] == '{' {
+			//a single request, as sent in 1.0 mode, is answered with a single object
+			r = make([]RpcResponse, 1)
+			err = c.Unmarshal(b, &r[0])
+		} else {
+			err = c.Unmarshal(b, &r)
+		}
+		if err != nil {
+			//bodies may go back to the buffer pool after parsing, so the error holds a copy
+			return nil, &ParseError{Body: append([]byte(nil), b...), Err: err}
+		}
+		if v != nil {
+			if err := v.check(b, r); err != nil {
+				return nil, err
+			}
+		}
+		for i := range r {
+			//1.0 servers send "result": null alongside an error
+			if r[i].Error != nil && string(r[i].Result) == "null" {
+				r[i].Result = nil
+			}
 		}
 		resps = append(resps, r...)
 	}
@@ -263,6 +766,6 @@ func parseBatch(bs [][]byte) ([]RpcResponse, error) {
 
 func addDefaultHeaders(req *fasthttp.Request) {
 	req.Header.SetMethod(fasthttp.MethodPost)
-	req.Header.SetContentType("application/json")
-	req.Header.Set("Accept-Encoding", "gzip")
+	req.Header.SetContentType(jsonContentType)
+	req.Header.Set("Accept-Encoding", acceptEncoding)
 }
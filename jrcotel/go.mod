@@ -0,0 +1,25 @@
+module github.com/cfoxon/jrc/jrcotel
+
+go 1.20
+
+require (
+	github.com/cfoxon/jrc v0.0.0-20261014070221-b9ac93529c4f
+	go.opentelemetry.io/otel v1.24.0
+	go.opentelemetry.io/otel/trace v1.24.0
+)
+
+require (
+	github.com/andybalholm/brotli v1.0.4 // indirect
+	github.com/go-logr/logr v1.4.1 // indirect
+	github.com/go-logr/stdr v1.2.2 // indirect
+	github.com/goccy/go-json v0.10.0 // indirect
+	github.com/klauspost/compress v1.15.9 // indirect
+	github.com/valyala/bytebufferpool v1.0.0 // indirect
+	github.com/valyala/fasthttp v1.43.0 // indirect
+	go.opentelemetry.io/otel/metric v1.24.0 // indirect
+	golang.org/x/net v0.0.0-20220906165146-f3363e06e74c // indirect
+	golang.org/x/oauth2 v0.20.0 // indirect
+	golang.org/x/text v0.3.7 // indirect
+)
+
+replace github.com/cfoxon/jrc => ../
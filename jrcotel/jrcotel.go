@@ -0,0 +1,62 @@
+//Package jrcotel traces jrc Servers with OpenTelemetry
+package jrcotel
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/cfoxon/jrc"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const instrumentation = "github.com/cfoxon/jrc/jrcotel"
+
+//Tracer is a jrc.Tracer that records OpenTelemetry spans and propagates the trace context in request headers
+type Tracer struct {
+	tracer trace.Tracer
+	prop   propagation.TextMapPropagator
+}
+
+//New creates a Tracer using tp and prop, or the global TracerProvider and TextMapPropagator when they are nil
+func New(tp trace.TracerProvider, prop propagation.TextMapPropagator) *Tracer {
+	if tp == nil {
+		tp = otel.GetTracerProvider()
+	}
+	if prop == nil {
+		prop = otel.GetTextMapPropagator()
+	}
+	return &Tracer{tracer: tp.Tracer(instrumentation), prop: prop}
+}
+
+//StartBatch implements jrc.Tracer
+func (t *Tracer) StartBatch(ctx context.Context, calls int) (context.Context, func(err error)) {
+	ctx, span := t.tracer.Start(ctx, "jrc.batch", trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attribute.String("rpc.system", "jsonrpc"), attribute.Int("jrc.calls", calls)))
+	return ctx, end(span)
+}
+
+//StartCall implements jrc.Tracer
+func (t *Tracer) StartCall(ctx context.Context, m *jrc.Message) (context.Context, func(err error)) {
+	ctx, span := t.tracer.Start(ctx, "jrc.call", trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attribute.String("rpc.system", "jsonrpc"), attribute.String("url.full", m.URL),
+			attribute.Int("http.request.body.size", len(m.Body))))
+	if m.Header == nil {
+		m.Header = make(http.Header)
+	}
+	t.prop.Inject(ctx, propagation.HeaderCarrier(m.Header))
+	return ctx, end(span)
+}
+
+func end(span trace.Span) func(err error) {
+	return func(err error) {
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+	}
+}
@@ -0,0 +1,82 @@
+//Package jrcprom exports the metrics of jrc Servers to Prometheus
+package jrcprom
+
+import (
+	"strconv"
+
+	"github.com/cfoxon/jrc"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+//Collector is a jrc.Metrics that keeps Prometheus counters and histograms
+//  register it with a prometheus.Registerer and pass it to jrc.Instrument
+type Collector struct {
+	requests  *prometheus.CounterVec
+	calls     prometheus.Counter
+	batchSize prometheus.Histogram
+	errors    *prometheus.CounterVec
+	rpcErrors *prometheus.CounterVec
+	latency   *prometheus.HistogramVec
+	bytesOut  prometheus.Counter
+	bytesIn   prometheus.Counter
+}
+
+//New creates a Collector whose metrics are named namespace_jrc_*, or jrc_* for an empty namespace
+func New(namespace string) *Collector {
+	counter := func(name, help string) prometheus.CounterOpts {
+		return prometheus.CounterOpts{Namespace: namespace, Subsystem: "jrc", Name: name, Help: help}
+	}
+	histogram := func(name, help string, buckets []float64) prometheus.HistogramOpts {
+		return prometheus.HistogramOpts{Namespace: namespace, Subsystem: "jrc", Name: name, Help: help, Buckets: buckets}
+	}
+	return &Collector{
+		requests:  prometheus.NewCounterVec(counter("requests_total", "RPC requests sent, by method."), []string{"method"}),
+		calls:     prometheus.NewCounter(counter("calls_total", "HTTP calls made, each carrying a batch of requests.")),
+		batchSize: prometheus.NewHistogram(histogram("batch_size", "Requests per HTTP call.", prometheus.ExponentialBuckets(1, 2, 10))),
+		errors:    prometheus.NewCounterVec(counter("errors_total", "HTTP calls that failed, by kind."), []string{"kind"}),
+		rpcErrors: prometheus.NewCounterVec(counter("rpc_errors_total", "Error responses from the server, by code."), []string{"code"}),
+		latency:   prometheus.NewHistogramVec(histogram("latency_seconds", "Latency of the HTTP calls carrying each method.", prometheus.DefBuckets), []string{"method"}),
+		bytesOut:  prometheus.NewCounter(counter("sent_bytes_total", "Request body bytes sent.")),
+		bytesIn:   prometheus.NewCounter(counter("received_bytes_total", "Response body bytes received.")),
+	}
+}
+
+func (c *Collector) collectors() []prometheus.Collector {
+	return []prometheus.Collector{c.requests, c.calls, c.batchSize, c.errors, c.rpcErrors, c.latency, c.bytesOut, c.bytesIn}
+}
+
+//Describe implements prometheus.Collector
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
+	for _, m := range c.collectors() {
+		m.Describe(ch)
+	}
+}
+
+//Collect implements prometheus.Collector
+func (c *Collector) Collect(ch chan<- prometheus.Metric) {
+	for _, m := range c.collectors() {
+		m.Collect(ch)
+	}
+}
+
+//ObserveCall implements jrc.Metrics
+func (c *Collector) ObserveCall(s *jrc.CallStats) {
+	c.calls.Inc()
+	c.batchSize.Observe(float64(len(s.Methods)))
+	c.bytesOut.Add(float64(s.BytesOut))
+	c.bytesIn.Add(float64(s.BytesIn))
+	if s.Err != nil {
+		c.errors.WithLabelValues(jrc.ErrorKind(s.Err)).Inc()
+	}
+	for _, e := range s.RPCErrors {
+		c.rpcErrors.WithLabelValues(strconv.Itoa(e.Code)).Inc()
+	}
+	seen := make(map[string]bool, len(s.Methods))
+	for _, m := range s.Methods {
+		c.requests.WithLabelValues(m).Inc()
+		if !seen[m] {
+			seen[m] = true
+			c.latency.WithLabelValues(m).Observe(s.Latency.Seconds())
+		}
+	}
+}
@@ -0,0 +1,246 @@
+//Package jrctest runs an in-process JSON RPC server for testing code that uses jrc
+//  register the results, errors and delays each method should answer with, then point a jrc.Server at URL
+package jrctest
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"time"
+
+	"github.com/cfoxon/jrc"
+	"github.com/cfoxon/jrc/internal/json"
+)
+
+//HandlerFunc answers one call of a method, with an error of type *jrc.RpcError sent as is and any other as an internal error
+type HandlerFunc func(ctx context.Context, params json.RawMessage) (interface{}, error)
+
+//Server is a mock JSON RPC 2.0 server answering single requests, batches and notifications over HTTP
+//  methods with nothing registered are answered with jrc.CodeMethodNotFound
+type Server struct {
+	URL string //the address to pass to jrc.NewServer
+
+	ts *httptest.Server
+
+	mu       sync.Mutex
+	handlers map[string]HandlerFunc
+	delays   map[string]time.Duration
+	fail     []int //HTTP statuses to answer the next posts with
+	requests []jrc.RpcRequest
+	posts    int
+}
+
+//request is a request or notification as the Server received it
+type request struct {
+	JsonRpc string          `json:"jsonrpc"`
+	Id      json.RawMessage `json:"id"` //absent for a notification, which is not the same as null
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params"`
+}
+
+//id returns the request's id and whether it expects a response
+func (q *request) id() (jrc.ID, bool) {
+	var id jrc.ID
+	if q.Id == nil {
+		return id, false
+	}
+	json.Unmarshal(q.Id, &id)
+	return id, true
+}
+
+//NewServer starts a Server, which should be closed when the test is done
+func NewServer() *Server {
+	s := &Server{handlers: make(map[string]HandlerFunc), delays: make(map[string]time.Duration)}
+	s.ts = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	s.URL = s.ts.URL
+	return s
+}
+
+//Client returns a jrc.Server pointed at s, with the given options
+func (s *Server) Client(options ...func(*jrc.Server) error) (*jrc.Server, error) {
+	return jrc.NewServer(s.URL, options...)
+}
+
+//Close shuts the Server down
+func (s *Server) Close() {
+	s.ts.Close()
+}
+
+//Handle answers calls of method with h
+func (s *Server) Handle(method string, h HandlerFunc) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.handlers[method] = h
+}
+
+//Result answers every call of method with result
+func (s *Server) Result(method string, result interface{}) {
+	s.Handle(method, func(context.Context, json.RawMessage) (interface{}, error) {
+		return result, nil
+	})
+}
+
+//Error answers every call of method with a JSON RPC error
+func (s *Server) Error(method string, code int, message string) {
+	s.Handle(method, func(context.Context, json.RawMessage) (interface{}, error) {
+		return nil, &jrc.RpcError{Code: code, Message: message}
+	})
+}
+
+//Echo answers every call of method with its own params
+func (s *Server) Echo(method string) {
+	s.Handle(method, func(_ context.Context, params json.RawMessage) (interface{}, error) {
+		return params, nil
+	})
+}
+
+//Delay holds back the response to any post holding a call of method by d
+//  a batch waits for the longest delay among its calls
+func (s *Server) Delay(method string, d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.delays[method] = d
+}
+
+//FailNext answers the next posts with the given HTTP statuses, one each, without looking at them
+func (s *Server) FailNext(statuses ...int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.fail = append(s.fail, statuses...)
+}
+
+//Requests returns every request and notification received so far, in order, with Params as json.RawMessage
+func (s *Server) Requests() []jrc.RpcRequest {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]jrc.RpcRequest(nil), s.requests...)
+}
+
+//Posts returns the number of HTTP requests received so far, one for each single call or batch
+func (s *Server) Posts() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.posts
+}
+
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	s.mu.Lock()
+	s.posts++
+	if len(s.fail) > 0 {
+		status := s.fail[0]
+		s.fail = s.fail[1:]
+		s.mu.Unlock()
+		w.WriteHeader(status)
+		return
+	}
+	s.mu.Unlock()
+
+	var reqs []request
+	batch := len(bytes.TrimSpace(body)) > 0 && bytes.TrimSpace(body)[0] == '['
+	if batch {
+		err = json.Unmarshal(body, &reqs)
+	} else {
+		reqs = make([]request, 1)
+		err = json.Unmarshal(body, &reqs[0])
+	}
+	if err != nil || batch && len(reqs) == 0 {
+		code, msg := jrc.CodeParseError, "Parse error"
+		if err == nil {
+			code, msg = jrc.CodeInvalidRequest, "Invalid Request"
+		}
+		writeJSON(w, jrc.RpcResponse{JSONRPC: "2.0", Error: &jrc.RpcError{Code: code, Message: msg}})
+		return
+	}
+
+	s.mu.Lock()
+	var delay time.Duration
+	for _, q := range reqs {
+		rr := jrc.RpcRequest{JsonRpc: q.JsonRpc, Method: q.Method}
+		id, _ := q.id()
+		rr.SetID(id)
+		if len(q.Params) > 0 {
+			rr.Params = q.Params
+		}
+		s.requests = append(s.requests, rr)
+		if d := s.delays[q.Method]; d > delay {
+			delay = d
+		}
+	}
+	s.mu.Unlock()
+	if delay > 0 {
+		select {
+		case <-time.After(delay):
+		case <-r.Context().Done():
+			return
+		}
+	}
+
+	resps := make([]jrc.RpcResponse, 0, len(reqs))
+	for _, q := range reqs {
+		resp := s.call(r.Context(), q)
+		if _, ok := q.id(); ok {
+			resps = append(resps, resp)
+		}
+	}
+	switch {
+	case len(resps) == 0:
+		//only notifications, so nothing to answer
+		w.WriteHeader(http.StatusNoContent)
+	case batch:
+		writeJSON(w, resps)
+	default:
+		writeJSON(w, resps[0])
+	}
+}
+
+//call answers a single request with its method's handler
+func (s *Server) call(ctx context.Context, q request) jrc.RpcResponse {
+	resp := jrc.RpcResponse{JSONRPC: "2.0"}
+	resp.ID, _ = q.id()
+	if q.JsonRpc != "2.0" || q.Method == "" {
+		resp.Error = &jrc.RpcError{Code: jrc.CodeInvalidRequest, Message: "Invalid Request"}
+		return resp
+	}
+	s.mu.Lock()
+	h := s.handlers[q.Method]
+	s.mu.Unlock()
+	if h == nil {
+		resp.Error = &jrc.RpcError{Code: jrc.CodeMethodNotFound, Message: "Method not found"}
+		return resp
+	}
+	result, err := h(ctx, q.Params)
+	var rpcErr *jrc.RpcError
+	switch {
+	case errors.As(err, &rpcErr):
+		resp.Error = rpcErr
+	case err != nil:
+		resp.Error = &jrc.RpcError{Code: jrc.CodeInternalError, Message: err.Error()}
+	default:
+		b, err := json.Marshal(result)
+		if err != nil {
+			resp.Error = &jrc.RpcError{Code: jrc.CodeInternalError, Message: err.Error()}
+			break
+		}
+		resp.Result = b
+	}
+	return resp
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(b)
+}
@@ -0,0 +1,45 @@
+package jrc
+
+import (
+	"bytes"
+	"errors"
+	"io"
+)
+
+//ErrResponseTooLarge is returned when a response body is bigger than MaxResponseSize
+var ErrResponseTooLarge = errors.New("jrc: response exceeds MaxResponseSize")
+
+func (srv *Server) setMaxResponseSize(n int) error {
+	if n < 0 {
+		return errors.New("jrc: MaxResponseSize cannot be negative")
+	}
+	srv.maxResp = n
+	//fasthttp stops reading at its own limit, before the body is buffered
+	srv.hc.MaxResponseBodySize = n
+	return nil
+}
+
+//MaxResponseSize aborts any call whose response body, after decompression, is bigger than n bytes, zero for no limit
+//  the call fails with ErrResponseTooLarge and is not retried
+func MaxResponseSize(n int) func(server *Server) error {
+	return func(srv *Server) error {
+		return srv.setMaxResponseSize(n)
+	}
+}
+
+//readLimited reads all of r into buf, failing with ErrResponseTooLarge beyond limit bytes when limit is positive
+//  buf may be nil, it is only grown if too small
+func readLimited(r io.Reader, limit int, buf []byte) ([]byte, error) {
+	if limit > 0 {
+		r = io.LimitReader(r, int64(limit)+1)
+	}
+	w := bytes.NewBuffer(buf)
+	if _, err := w.ReadFrom(r); err != nil {
+		return nil, err
+	}
+	b := w.Bytes()
+	if limit > 0 && len(b) > limit {
+		return nil, ErrResponseTooLarge
+	}
+	return b, nil
+}
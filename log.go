@@ -0,0 +1,55 @@
+package jrc
+
+//LogLevel is the verbosity of a message passed to a Logger
+type LogLevel int
+
+const (
+	LogError LogLevel = iota //calls that failed for good
+	LogWarn                  //retries, endpoint failures and failed health checks
+	LogInfo                  //endpoints recovering
+	LogDebug                 //every dispatch and HTTP request
+)
+
+//String returns the lower case name of the level
+func (l LogLevel) String() string {
+	switch l {
+	case LogError:
+		return "error"
+	case LogWarn:
+		return "warn"
+	case LogInfo:
+		return "info"
+	case LogDebug:
+		return "debug"
+	}
+	return "unknown"
+}
+
+//Logger receives structured messages about what a Server is doing
+//  keyvals alternate between string keys and their values; Log must be safe for concurrent use
+type Logger interface {
+	Log(level LogLevel, msg string, keyvals ...interface{})
+}
+
+//LoggerFunc adapts a function to a Logger
+type LoggerFunc func(level LogLevel, msg string, keyvals ...interface{})
+
+//Log calls f
+func (f LoggerFunc) Log(level LogLevel, msg string, keyvals ...interface{}) {
+	f(level, msg, keyvals...)
+}
+
+//UseLogger sends the Server's messages up to the given verbosity to l, nil keeps the Server silent
+func UseLogger(l Logger, verbosity LogLevel) func(server *Server) error {
+	return func(srv *Server) error {
+		srv.logger = l
+		srv.verbosity = verbosity
+		return nil
+	}
+}
+
+func (srv *Server) log(level LogLevel, msg string, keyvals ...interface{}) {
+	if srv.logger != nil && level <= srv.verbosity {
+		srv.logger.Log(level, msg, keyvals...)
+	}
+}
@@ -0,0 +1,88 @@
+package jrc
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"time"
+
+	"github.com/cfoxon/jrc/internal/json"
+)
+
+//CallStats describes one HTTP call made by a Server, which may carry a batch of requests
+type CallStats struct {
+	Methods   []string      //method of each request in the call, in order
+	BytesOut  int           //size of the request body as sent, after any compression
+	BytesIn   int           //size of the response body after decoding
+	Latency   time.Duration //time taken including retries and failover
+	Err       error         //why no response came back, nil on success
+	RPCErrors []*RpcError   //error members of the responses
+}
+
+//Metrics receives the stats of every HTTP call made by a Server
+//  ObserveCall is called from the Server's workers and must be safe for concurrent use
+type Metrics interface {
+	ObserveCall(s *CallStats)
+}
+
+//ErrorKind names the category of an error in CallStats, for use as a metric label
+//  one of "timeout", "canceled", "circuit_open", "status", "transport", or "" for nil
+func ErrorKind(err error) string {
+	var se *StatusError
+	switch {
+	case err == nil:
+		return ""
+	case errors.Is(err, context.DeadlineExceeded):
+		return "timeout"
+	case errors.Is(err, context.Canceled):
+		return "canceled"
+	case errors.Is(err, ErrCircuitOpen):
+		return "circuit_open"
+	case errors.As(err, &se):
+		return "status"
+	}
+	return "transport"
+}
+
+//Instrument reports the stats of every HTTP call to m, nil stops reporting
+func Instrument(m Metrics) func(server *Server) error {
+	return func(srv *Server) error {
+		srv.metrics = m
+		return nil
+	}
+}
+
+//observe builds the CallStats for c and hands them to the Server's Metrics
+func (srv *Server) observe(c *call, b []byte, err error, latency time.Duration) {
+	s := &CallStats{BytesOut: len(c.msg.Body), BytesIn: len(b), Latency: latency, Err: err}
+	var reqs []struct {
+		Method string `json:"method"`
+	}
+	if unmarshalBatch(c.body, &reqs) == nil {
+		s.Methods = make([]string, len(reqs))
+		for i, r := range reqs {
+			s.Methods[i] = r.Method
+		}
+	}
+	var resps []struct {
+		Error *RpcError `json:"error"`
+	}
+	if len(b) > 0 && unmarshalBatch(b, &resps) == nil {
+		for _, r := range resps {
+			if r.Error != nil {
+				s.RPCErrors = append(s.RPCErrors, r.Error)
+			}
+		}
+	}
+	srv.metrics.ObserveCall(s)
+}
+
+//unmarshalBatch decodes a batch b into vs, or a single object into a batch of one,
+//  as 1.0 calls, BareSingle and FallbackToSingle send and are answered with
+func unmarshalBatch[T any](b []byte, vs *[]T) error {
+	if b = bytes.TrimSpace(b); len(b) > 0 && b[0] == '{' {
+		*vs = make([]T, 1)
+		return json.Unmarshal(b, &(*vs)[0])
+	}
+	return json.Unmarshal(b, vs)
+}
@@ -0,0 +1,29 @@
+package jrc
+
+import "context"
+
+//Handler sends the body of one HTTP call and returns the response body
+type Handler func(ctx context.Context, m *Message) ([]byte, error)
+
+//Middleware wraps a Handler to change requests, answer them itself without calling next, or watch the responses
+type Middleware func(next Handler) Handler
+
+//Use adds middleware around every HTTP call, the first one given being the outermost
+//  middleware runs before retries, the circuit breaker and failover; m.URL is empty as the endpoint is not chosen yet
+func Use(mw ...Middleware) func(server *Server) error {
+	return func(srv *Server) error {
+		srv.middleware = append(srv.middleware, mw...)
+		return nil
+	}
+}
+
+//buildHandler chains the Server's middleware in front of its retrying round trip
+func (srv *Server) buildHandler() {
+	h := Handler(func(ctx context.Context, m *Message) ([]byte, error) {
+		return srv.roundTrip(&call{ctx: ctx, msg: m})
+	})
+	for i := len(srv.middleware) - 1; i >= 0; i-- {
+		h = srv.middleware[i](h)
+	}
+	srv.handler = h
+}
@@ -0,0 +1,90 @@
+package jrc
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+)
+
+//NetHTTPTransport sends messages with net/http instead of fasthttp
+//  for HTTP/2, proxies from the environment, httptrace and other standard library features
+type NetHTTPTransport struct {
+	Client *http.Client //http.DefaultClient when nil
+}
+
+//Close drops the idle connections of t.Client, leaving http.DefaultClient alone as it is shared
+func (t *NetHTTPTransport) Close() error {
+	if t.Client != nil {
+		t.Client.CloseIdleConnections()
+	}
+	return nil
+}
+
+//RoundTrip implements Transport
+func (t *NetHTTPTransport) RoundTrip(ctx context.Context, m *Message) ([]byte, error) {
+	var body io.Reader
+	if m.Query == nil {
+		body = bytes.NewReader(m.Body)
+	}
+	req, err := http.NewRequestWithContext(ctx, m.method(), m.target(), body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", jsonContentType)
+	//setting Accept-Encoding turns off net/http's transparent gzip, so every encoding is decoded here
+	req.Header.Set("Accept-Encoding", acceptEncoding)
+	for k, vs := range m.Header {
+		req.Header[k] = vs
+	}
+	if m.Query != nil {
+		req.Header.Del("Content-Type")
+	}
+	if m.Jar != nil {
+		for _, c := range m.Jar.Cookies(req.URL) {
+			req.AddCookie(c)
+		}
+	}
+	c := t.Client
+	if c == nil {
+		c = http.DefaultClient
+	}
+	resp, err := c.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if m.Response != nil {
+		for k, vs := range resp.Header {
+			m.Response[k] = append(m.Response[k], vs...)
+		}
+	}
+	if m.Jar != nil {
+		if cs := resp.Cookies(); len(cs) > 0 {
+			m.Jar.SetCookies(req.URL, cs)
+		}
+	}
+	var buf []byte
+	if m.Pooled {
+		buf = getBuffer(int(resp.ContentLength))
+	}
+	raw, err := readLimited(resp.Body, m.MaxResponseSize, buf)
+	if err != nil {
+		return nil, err
+	}
+	b, err := decodeBody(resp.Header.Get("Content-Encoding"), raw, m.MaxResponseSize)
+	if err != nil {
+		return nil, err
+	}
+	if m.Pooled && len(raw) > 0 && (len(b) == 0 || &b[0] != &raw[0]) {
+		//the compressed body is no longer needed
+		putBuffer(raw)
+	}
+	if resp.StatusCode == http.StatusUnauthorized && m.Challenge {
+		return nil, &StatusError{StatusCode: resp.StatusCode, Body: b, Header: resp.Header}
+	}
+	if resp.StatusCode >= http.StatusInternalServerError || resp.StatusCode == http.StatusRequestEntityTooLarge {
+		return nil, &StatusError{StatusCode: resp.StatusCode, Body: b}
+	}
+	return b, nil
+}
@@ -0,0 +1,44 @@
+package jrc
+
+import "context"
+
+type RPCNotifications []*RpcNotification
+
+//RpcNotification contains a JSON RPC 2.0 request without an id
+//  the Server does not reply to notifications
+type RpcNotification struct {
+	JsonRpc string      `json:"jsonrpc"`
+	Method  string      `json:"method"`
+	Params  interface{} `json:"params,omitempty"`
+}
+
+//Notify sends a single notification to the Server
+func (srv *Server) Notify(n RpcNotification, opts ...CallOption) error {
+	return srv.NotifyContext(context.Background(), n, opts...)
+}
+
+//NotifyContext is Notify with a context to cancel the call or apply a deadline
+func (srv *Server) NotifyContext(ctx context.Context, n RpcNotification, opts ...CallOption) error {
+	return srv.NotifyBatchContext(ctx, RPCNotifications{&n}, opts...)
+}
+
+//NotifyBatch sends a batch of notifications to the Server, batched according to MaxBatch
+func (srv *Server) NotifyBatch(ns RPCNotifications, opts ...CallOption) error {
+	return srv.NotifyBatchContext(context.Background(), ns, opts...)
+}
+
+//NotifyBatchContext is NotifyBatch with a context to cancel the batch or apply a deadline
+//  any response bodies are discarded, as the Server should not send any
+func (srv *Server) NotifyBatchContext(ctx context.Context, ns RPCNotifications, opts ...CallOption) error {
+	if len(ns) < 1 {
+		return nil
+	}
+	cfg := newCallConfig(opts)
+	cfg.notify = true
+	bodies, err := srv.marshalNotifications(ns, cfg.batchSize(srv))
+	if err != nil {
+		return err
+	}
+	_, err = srv.post(ctx, bodies, cfg)
+	return err
+}
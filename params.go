@@ -0,0 +1,58 @@
+package jrc
+
+import "errors"
+
+//NamedParams holds by-name params, sent as a JSON object
+type NamedParams map[string]interface{}
+
+//ErrMixedParams is returned when NamedParams are given alongside positional params
+var ErrMixedParams = errors.New("jrc: named and positional params cannot be mixed")
+
+//Params builds the params of a request from the arguments of a call
+//  no arguments give no params, NamedParams are sent by name (several are merged), anything else as an array in order
+func Params(params ...interface{}) (interface{}, error) {
+	if len(params) == 0 {
+		return nil, nil
+	}
+	var named NamedParams
+	for _, p := range params {
+		np, ok := p.(NamedParams)
+		if !ok {
+			continue
+		}
+		if named == nil {
+			named = make(NamedParams, len(np))
+		}
+		for k, v := range np {
+			named[k] = v
+		}
+	}
+	if named == nil {
+		return params, nil
+	}
+	for _, p := range params {
+		if _, ok := p.(NamedParams); !ok {
+			return nil, ErrMixedParams
+		}
+	}
+	return named, nil
+}
+
+//NewRequest creates a JSON RPC 2.0 request with params built by Params
+//  the Id is left null, to be set by the caller or AutoID
+func NewRequest(method string, params ...interface{}) (*RpcRequest, error) {
+	p, err := Params(params...)
+	if err != nil {
+		return nil, err
+	}
+	return &RpcRequest{JsonRpc: "2.0", Method: method, Params: p}, nil
+}
+
+//NewNotification creates a JSON RPC 2.0 notification with params built by Params
+func NewNotification(method string, params ...interface{}) (*RpcNotification, error) {
+	p, err := Params(params...)
+	if err != nil {
+		return nil, err
+	}
+	return &RpcNotification{JsonRpc: "2.0", Method: method, Params: p}, nil
+}
@@ -0,0 +1,82 @@
+package jrc
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"time"
+
+	"github.com/cfoxon/jrc/internal/json"
+)
+
+//defaultPollInterval is how long Poll waits between calls that bring nothing new when Poller.Interval is zero
+const defaultPollInterval = time.Second
+
+//Poller describes the calls Poll makes to follow a server without push notifications
+type Poller struct {
+	Method string
+	//Params returns the params of the call for what comes after cursor, which is nil for the first call
+	Params func(cursor json.RawMessage) []interface{}
+	//Cursor returns the cursor that follows result, such as the next block number or sequence
+	//  a result that leaves the cursor as it was brings nothing new and is not delivered
+	Cursor   func(result json.RawMessage) (json.RawMessage, error)
+	Interval time.Duration //wait after a call that brought nothing new, one second if zero
+}
+
+//Poll calls p.Method again and again, each time with the cursor of the result before, and delivers the results
+//  that move the cursor on as Events on a Subscription, as Subscribe does for servers that push them
+//  a result that moves it on is followed by the next call at once, to catch up; polling stops with Unsubscribe,
+//  with Close, when ctx is done or when a call or p.Cursor fails, which Err then returns
+func (srv *Server) Poll(ctx context.Context, p Poller) (*Subscription, error) {
+	if p.Method == "" || p.Params == nil || p.Cursor == nil {
+		return nil, errors.New("jrc: Poller needs a Method, Params and Cursor")
+	}
+	if p.Interval < 0 {
+		return nil, errors.New("jrc: Poller Interval cannot be negative")
+	}
+	if p.Interval == 0 {
+		p.Interval = defaultPollInterval
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	var s *Subscription
+	s = newSubscription(ID{}, func() error {
+		srv.events.remove(s)
+		cancel()
+		return nil
+	})
+	if !srv.events.add(s, cancel) {
+		cancel()
+		s.end(ErrClosed)
+		return nil, ErrClosed
+	}
+	go func() {
+		err := srv.poll(ctx, p, s)
+		srv.events.remove(s)
+		s.end(err)
+		cancel()
+	}()
+	return s, nil
+}
+
+//poll makes the calls of p until one fails or ctx is done
+func (srv *Server) poll(ctx context.Context, p Poller, s *Subscription) error {
+	var cursor json.RawMessage
+	for {
+		res, err := srv.Call(ctx, p.Method, p.Params(cursor)...)
+		if err != nil {
+			return err
+		}
+		next, err := p.Cursor(res)
+		if err != nil {
+			return err
+		}
+		if cursor != nil && bytes.Equal(next, cursor) {
+			if !sleep(ctx, p.Interval) {
+				return ctx.Err()
+			}
+			continue
+		}
+		cursor = append(json.RawMessage(nil), next...)
+		s.deliver(Event{Method: p.Method, Result: res})
+	}
+}
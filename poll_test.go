@@ -0,0 +1,103 @@
+package jrc_test
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/cfoxon/jrc"
+	"github.com/cfoxon/jrc/jrctest"
+	"github.com/goccy/go-json"
+)
+
+func TestPoll(t *testing.T) {
+	ts := jrctest.NewServer()
+	defer ts.Close()
+	var head, calls int32 = 3, 0
+	//get_ops returns the numbers from its param up to the head, and the number to ask from next
+	ts.Handle("get_ops", func(_ context.Context, params json.RawMessage) (interface{}, error) {
+		atomic.AddInt32(&calls, 1)
+		var from []int32
+		json.Unmarshal(params, &from)
+		var ops []int32
+		for i := from[0]; i < atomic.LoadInt32(&head); i++ {
+			ops = append(ops, i)
+		}
+		return map[string]interface{}{"ops": ops, "next": from[0] + int32(len(ops))}, nil
+	})
+	srv, _ := ts.Client()
+	defer srv.Close()
+
+	sub, err := srv.Poll(context.Background(), jrc.Poller{
+		Method: "get_ops",
+		Params: func(cursor json.RawMessage) []interface{} {
+			if cursor == nil {
+				return []interface{}{0}
+			}
+			return []interface{}{cursor}
+		},
+		Cursor: func(result json.RawMessage) (json.RawMessage, error) {
+			var r struct{ Next json.RawMessage }
+			err := json.Unmarshal(result, &r)
+			return r.Next, err
+		},
+		Interval: 10 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ev := <-sub.C
+	if string(ev.Result) != `{"next":3,"ops":[0,1,2]}` {
+		t.Fatalf("first result %s", ev.Result)
+	}
+	//calls that bring nothing new are not delivered
+	time.Sleep(50 * time.Millisecond)
+	if n := atomic.LoadInt32(&calls); n < 3 {
+		t.Fatalf("%d calls while waiting, want a poll every 10ms", n)
+	}
+	atomic.StoreInt32(&head, 5)
+	select {
+	case ev = <-sub.C:
+	case <-time.After(time.Second):
+		t.Fatal("new ops not delivered")
+	}
+	if string(ev.Result) != `{"next":5,"ops":[3,4]}` {
+		t.Fatalf("second result %s", ev.Result)
+	}
+	sub.Unsubscribe()
+	for range sub.C {
+	}
+	if sub.Err() != nil {
+		t.Errorf("ended with %v after Unsubscribe", sub.Err())
+	}
+	//a call in flight may still reach the server, but none is made after it
+	time.Sleep(20 * time.Millisecond)
+	n := atomic.LoadInt32(&calls)
+	time.Sleep(50 * time.Millisecond)
+	if m := atomic.LoadInt32(&calls); m != n {
+		t.Errorf("%d calls after Unsubscribe", m-n)
+	}
+}
+
+func TestPollStopsOnError(t *testing.T) {
+	ts := jrctest.NewServer()
+	defer ts.Close()
+	ts.Error("get_ops", jrc.CodeInvalidParams, "bad cursor")
+	srv, _ := ts.Client()
+	defer srv.Close()
+
+	sub, _ := srv.Poll(context.Background(), jrc.Poller{
+		Method: "get_ops",
+		Params: func(json.RawMessage) []interface{} { return nil },
+		Cursor: func(r json.RawMessage) (json.RawMessage, error) { return r, nil },
+	})
+	for range sub.C {
+	}
+	if e, ok := sub.Err().(*jrc.RpcError); !ok || e.Code != jrc.CodeInvalidParams {
+		t.Errorf("ended with %v, want the RpcError", sub.Err())
+	}
+	if _, err := srv.Poll(context.Background(), jrc.Poller{Method: "get_ops"}); err == nil {
+		t.Error("Poller without Params and Cursor accepted")
+	}
+}
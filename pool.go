@@ -0,0 +1,90 @@
+package jrc
+
+import "errors"
+
+//ErrClosed is returned for calls made on a Server after Close
+var ErrClosed = errors.New("jrc: server closed")
+
+//startWorkers brings the Server's pool of workers up to MaxCon, stopping the extra ones if it shrank
+//  the pool is shared by every call on the Server and lives until Close; the returned channel is closed when it stops
+func (srv *Server) startWorkers() (chan struct{}, error) {
+	srv.poolMu.Lock()
+	defer srv.poolMu.Unlock()
+	if srv.closed {
+		return nil, ErrClosed
+	}
+	if srv.quit == nil {
+		srv.quit = make(chan struct{})
+	}
+	for ; srv.workers < srv.conn; srv.workers++ {
+		srv.poolWg.Add(1)
+		go srv.client(srv.quit, srv.shrink)
+	}
+	for ; srv.workers > srv.conn; srv.workers-- {
+		go func(quit chan struct{}) {
+			select {
+			case srv.shrink <- struct{}{}:
+			case <-quit:
+			}
+		}(srv.quit)
+	}
+	return srv.quit, nil
+}
+
+//extraWorkers starts n workers on top of the pool for a batch with a higher WithMaxCon, which stop when the returned func is called
+func (srv *Server) extraWorkers(n int) (func(), error) {
+	srv.poolMu.Lock()
+	defer srv.poolMu.Unlock()
+	if srv.closed {
+		return nil, ErrClosed
+	}
+	quit := make(chan struct{})
+	for i := 0; i < n; i++ {
+		srv.poolWg.Add(1)
+		go srv.client(quit, nil)
+	}
+	return func() { close(quit) }, nil
+}
+
+//stopWorkers stops the pool for good, reporting whether it was running; the workers finish the calls they are
+//  making before stopping, see poolWg
+func (srv *Server) stopWorkers() bool {
+	srv.poolMu.Lock()
+	if srv.closed {
+		srv.poolMu.Unlock()
+		return false
+	}
+	srv.closed = true
+	if srv.quit != nil {
+		close(srv.quit)
+		srv.quit = nil
+		srv.workers = 0
+	}
+	srv.poolMu.Unlock()
+	return true
+}
+
+func (srv *Server) setQueueSize(n int) error {
+	if n < 0 {
+		return errors.New("jrc: QueueSize cannot be negative")
+	}
+	srv.poolMu.Lock()
+	defer srv.poolMu.Unlock()
+	if srv.quit != nil || srv.closed {
+		//the workers are already receiving on the current queue
+		return errors.New("jrc: QueueSize must be set before the first call")
+	}
+	if n != cap(srv.reqc) {
+		srv.reqc = make(chan *call, n)
+	}
+	return nil
+}
+
+//QueueSize buffers up to n calls between the batches being sent and the workers, zero by default
+//  with a queue, a batch can hand its next calls over while every worker is busy rather than waiting for one to be free
+//  it can only be set before the first call, as the workers take their calls from it
+func QueueSize(n int) func(server *Server) error {
+	return func(srv *Server) error {
+		return srv.setQueueSize(n)
+	}
+}
@@ -0,0 +1,48 @@
+package jrc_test
+
+import (
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/cfoxon/jrc"
+	"github.com/cfoxon/jrc/jrctest"
+)
+
+func twoRequests() jrc.RPCRequests {
+	return jrc.RPCRequests{{JsonRpc: "2.0", Id: 1, Method: "echo", Params: []int{1}}, {JsonRpc: "2.0", Id: 2, Method: "echo", Params: []int{2}}}
+}
+
+//settledGoroutines waits briefly for goroutines that are finishing, returning how many are left
+func settledGoroutines() int {
+	time.Sleep(50 * time.Millisecond)
+	return runtime.NumGoroutine()
+}
+
+func TestWorkersAreSharedAcrossBatches(t *testing.T) {
+	ts := jrctest.NewServer()
+	defer ts.Close()
+	ts.Echo("echo")
+	srv, _ := ts.Client(jrc.MaxBatch(1), jrc.MaxCon(8))
+	defer srv.Close()
+
+	if _, err := srv.ExecBatchFast(twoRequests()); err != nil {
+		t.Fatal(err)
+	}
+	before := settledGoroutines()
+	for i := 0; i < 100; i++ {
+		if _, err := srv.ExecBatchFast(twoRequests()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if after := settledGoroutines(); after > before {
+		t.Errorf("%d goroutines after 100 batches, %d before", after, before)
+	}
+
+	//shrinking MaxCon stops the extra workers
+	srv.SetOption(jrc.MaxCon(2))
+	srv.ExecBatchFast(twoRequests())
+	if after := settledGoroutines(); after > before-6 {
+		t.Errorf("%d goroutines after shrinking the pool from 8 to 2, %d before", after, before)
+	}
+}
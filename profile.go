@@ -0,0 +1,105 @@
+package jrc
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"time"
+)
+
+//MethodProfile overrides how the requests of some methods are sent, see Profile
+type MethodProfile struct {
+	Timeout   time.Duration //limit on each HTTP call carrying the methods, retries included, zero for none
+	Retry     *RetryPolicy  //used in place of the Server's Retry when set
+	Endpoints []string      //the only endpoints the methods are sent to, of those given to NewServer and Endpoints or found by Discover; all when empty
+}
+
+//profile is a MethodProfile ready for use, shared by the methods it was set for
+type profile struct {
+	MethodProfile
+	urls map[string]bool
+}
+
+//errNoProfileEndpoint fails calls whose profile names none of the Server's current endpoints
+var errNoProfileEndpoint = errors.New("jrc: none of the endpoints of the method's profile is among the Server's")
+
+func (srv *Server) setProfile(mp MethodProfile, methods []string) error {
+	if mp.Timeout < 0 {
+		return errors.New("jrc: profile timeout cannot be negative")
+	}
+	if mp.Retry != nil {
+		if err := mp.Retry.check(); err != nil {
+			return err
+		}
+	}
+	p := &profile{MethodProfile: mp}
+	if len(mp.Endpoints) > 0 {
+		p.urls = make(map[string]bool, len(mp.Endpoints))
+		for _, addr := range mp.Endpoints {
+			u, err := url.Parse(addr)
+			if err != nil {
+				return err
+			}
+			p.urls[u.String()] = true
+		}
+	}
+	if srv.profiles == nil {
+		srv.profiles = make(map[string]*profile)
+	}
+	for _, m := range methods {
+		srv.profiles[m] = p
+	}
+	return nil
+}
+
+//Profile sends the requests of the given methods as mp says rather than as the Server's settings do,
+//  so an expensive call such as get_block_range can have a longer timeout and go to archive nodes only
+//  requests with different profiles never share an HTTP call, so a batch is split wherever the profile changes
+//  from one request to the next, and keeping the requests of each method together keeps the calls few
+func Profile(mp MethodProfile, methods ...string) func(server *Server) error {
+	return func(srv *Server) error {
+		return srv.setProfile(mp, methods)
+	}
+}
+
+//subProfiles returns the profile of each of subs, nil when the Server has none
+func (srv *Server) subProfiles(subs []SubBatch) []*profile {
+	if len(srv.profiles) == 0 {
+		return nil
+	}
+	ps := make([]*profile, len(subs))
+	for i, sb := range subs {
+		ps[i] = srv.profiles[sb.Requests[0].Method]
+	}
+	return ps
+}
+
+//context applies the profile's timeout to ctx
+func (p *profile) context(ctx context.Context) (context.Context, context.CancelFunc) {
+	if p == nil || p.Timeout == 0 {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, p.Timeout)
+}
+
+//retry returns the profile's RetryPolicy, or def when it has none
+func (p *profile) retry(def RetryPolicy) RetryPolicy {
+	if p == nil || p.Retry == nil {
+		return def
+	}
+	return *p.Retry
+}
+
+//filter keeps the endpoints of eps the profile allows
+func (p *profile) filter(eps []*endpoint) []*endpoint {
+	if p == nil || p.urls == nil {
+		return eps
+	}
+	kept := make([]*endpoint, 0, len(eps))
+	for _, ep := range eps {
+		if p.urls[ep.url.String()] {
+			kept = append(kept, ep)
+		}
+	}
+	return kept
+}
@@ -0,0 +1,71 @@
+package jrc_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/cfoxon/jrc"
+	"github.com/goccy/go-json"
+)
+
+//namedNode answers every request with its name, after a delay for those of method slow
+func namedNode(t *testing.T, name string) string {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var rs []jrc.RpcRequest
+		json.NewDecoder(r.Body).Decode(&rs)
+		resps := make([]jrc.RpcResponse, len(rs))
+		for i, q := range rs {
+			if q.Method == "slow" {
+				time.Sleep(200 * time.Millisecond)
+			}
+			resps[i] = jrc.RpcResponse{JSONRPC: "2.0", ID: q.ID(), Result: json.RawMessage(`"` + name + `"`)}
+		}
+		json.NewEncoder(w).Encode(resps)
+	}))
+	t.Cleanup(ts.Close)
+	return ts.URL
+}
+
+func TestProfile(t *testing.T) {
+	light, archive := namedNode(t, "light"), namedNode(t, "archive")
+	srv, err := jrc.NewServer(light, jrc.Endpoints(archive), jrc.PreserveOrder(true),
+		jrc.Profile(jrc.MethodProfile{Endpoints: []string{archive}}, "get_block_range", "get_ops_in_block"),
+		jrc.Profile(jrc.MethodProfile{Timeout: 20 * time.Millisecond}, "slow"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer srv.Close()
+
+	rs := jrc.RPCRequests{
+		{JsonRpc: "2.0", Id: 1, Method: "status"},
+		{JsonRpc: "2.0", Id: 2, Method: "get_block_range"},
+		{JsonRpc: "2.0", Id: 3, Method: "get_ops_in_block"},
+		{JsonRpc: "2.0", Id: 4, Method: "status"},
+	}
+	//the batch is split where the profile changes, and the methods sharing one stay together
+	if subs := srv.Split(rs); len(subs) != 3 || len(subs[1].Requests) != 2 {
+		t.Fatalf("split into %+v", subs)
+	}
+	resps, err := srv.ExecBatch(rs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{`"light"`, `"archive"`, `"archive"`, `"light"`}
+	for i, resp := range resps {
+		if string(resp.Result) != want[i] {
+			t.Errorf("request %d answered by %s, want %s", i+1, resp.Result, want[i])
+		}
+	}
+
+	_, err = srv.Exec(jrc.RpcRequest{JsonRpc: "2.0", Id: 5, Method: "slow"})
+	if !errors.Is(err, jrc.ErrTimeout) {
+		t.Errorf("slow call failed with %v, want its profile's timeout", err)
+	}
+
+	if _, err := jrc.NewServer(light, jrc.Profile(jrc.MethodProfile{Retry: &jrc.RetryPolicy{Max: -1}}, "x")); err == nil {
+		t.Error("a bad retry policy was accepted")
+	}
+}
@@ -0,0 +1,71 @@
+package jrc
+
+//Progress is how far a batch has got, as passed to OnProgress
+type Progress struct {
+	Requests      int //in the HTTP calls of the batch, which leaves out cache hits
+	Sent          int //requests whose HTTP call a worker has taken
+	Received      int //requests whose responses have come back
+	Failed        int //requests whose HTTP call failed, see BatchError
+	BytesSent     int //of the request bodies taken by workers, as sent
+	BytesReceived int //of the response bodies that have come back, decoded
+}
+
+//OnProgress calls fn each time an HTTP call of one batch is taken by a worker and each time one comes back,
+//  so long scans can drive progress bars and logging; fn is called from the goroutine running the batch
+//  and holds it up, so it should return quickly; requests FallbackToSingle sends again are reported as a batch of their own
+func OnProgress(fn func(Progress)) CallOption {
+	return func(cfg *callConfig) {
+		cfg.progress = fn
+	}
+}
+
+//progress tracks a batch for OnProgress
+type progress struct {
+	Progress
+	fn    func(Progress)
+	sizes []int
+}
+
+//newProgress returns the tracker for the batch of cfg, nil if it has no OnProgress hook
+func newProgress(cfg *callConfig) *progress {
+	if cfg.progress == nil {
+		return nil
+	}
+	p := &progress{fn: cfg.progress, sizes: cfg.sizes}
+	for _, n := range cfg.sizes {
+		p.Requests += n
+	}
+	return p
+}
+
+//size is the number of requests in the i-th call
+func (p *progress) size(i int) int {
+	if i < len(p.sizes) {
+		return p.sizes[i]
+	}
+	return 0
+}
+
+//sent reports that a worker took the i-th call, with a body of n bytes
+func (p *progress) sent(i, n int) {
+	if p == nil {
+		return
+	}
+	p.Sent += p.size(i)
+	p.BytesSent += n
+	p.fn(p.Progress)
+}
+
+//done reports that the i-th call came back with b, or failed with err
+func (p *progress) done(i int, b []byte, err error) {
+	if p == nil {
+		return
+	}
+	if err != nil {
+		p.Failed += p.size(i)
+	} else {
+		p.Received += p.size(i)
+		p.BytesReceived += len(b)
+	}
+	p.fn(p.Progress)
+}
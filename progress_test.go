@@ -0,0 +1,106 @@
+package jrc_test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cfoxon/jrc"
+	"github.com/cfoxon/jrc/jrctest"
+)
+
+func TestOnProgress(t *testing.T) {
+	ts := jrctest.NewServer()
+	defer ts.Close()
+	ts.Echo("echo")
+	ts.FailNext(http.StatusInternalServerError)
+	srv, _ := ts.Client()
+	defer srv.Close()
+	rs := make(jrc.RPCRequests, 5)
+	for i := range rs {
+		rs[i] = &jrc.RpcRequest{JsonRpc: "2.0", Id: i + 1, Method: "echo", Params: []int{i}}
+	}
+	var seen []jrc.Progress
+	_, err := srv.ExecBatch(rs, jrc.WithMaxBatch(2), jrc.WithMaxCon(1), jrc.OnProgress(func(p jrc.Progress) {
+		seen = append(seen, p)
+	}))
+	var be *jrc.BatchError
+	if !errors.As(err, &be) {
+		t.Fatalf("got %v, want a BatchError for the first call", err)
+	}
+	//three calls, each reported once as it went out and once as it came back
+	if len(seen) != 6 {
+		t.Fatalf("reported %d times: %+v", len(seen), seen)
+	}
+	if p := seen[0]; p.Requests != 5 || p.Sent != 2 || p.Received != 0 || p.BytesSent == 0 {
+		t.Errorf("first report %+v", p)
+	}
+	last := seen[len(seen)-1]
+	if last.Sent != 5 || last.Failed != 2 || last.Received != 3 || last.BytesReceived == 0 {
+		t.Errorf("last report %+v", last)
+	}
+	for i := 1; i < len(seen); i++ {
+		if seen[i].Sent < seen[i-1].Sent || seen[i].Received+seen[i].Failed < seen[i-1].Received+seen[i-1].Failed {
+			t.Errorf("progress went backwards: %+v then %+v", seen[i-1], seen[i])
+		}
+	}
+}
+
+func TestWithStats(t *testing.T) {
+	var mu sync.Mutex
+	posts := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		posts++
+		n := posts
+		mu.Unlock()
+		if n == 1 {
+			//dropping the connection is a transport error, which is retried, and net/http leaves retrying a post to the Server
+			c, _, _ := w.(http.Hijacker).Hijack()
+			c.Close()
+			return
+		}
+		var rs []jrc.RpcRequest
+		json.NewDecoder(r.Body).Decode(&rs)
+		out := make([]jrc.RpcResponse, len(rs))
+		for i, q := range rs {
+			out[i] = jrc.RpcResponse{JSONRPC: "2.0", ID: jrc.IntID(q.Id), Result: json.RawMessage("true")}
+		}
+		time.Sleep(10 * time.Millisecond)
+		json.NewEncoder(w).Encode(out)
+	}))
+	defer ts.Close()
+	srv, _ := jrc.NewServer(ts.URL, jrc.UseTransport(&jrc.NetHTTPTransport{}), jrc.MaxCon(1), jrc.Retry(jrc.RetryPolicy{Max: 1, Base: time.Millisecond}))
+	defer srv.Close()
+	rs := make(jrc.RPCRequests, 5)
+	for i := range rs {
+		rs[i] = &jrc.RpcRequest{JsonRpc: "2.0", Id: i + 1, Method: "ok"}
+	}
+	var st jrc.BatchStats
+	if _, err := srv.ExecBatch(rs, jrc.WithMaxBatch(2), jrc.WithStats(&st)); err != nil {
+		t.Fatal(err)
+	}
+	if st.Calls != 3 || st.Sends != 4 || st.Retries != 1 || st.BytesOut == 0 || st.BytesIn == 0 {
+		t.Errorf("stats %+v", st)
+	}
+	if len(st.Latency) != 3 || st.Wall < 30*time.Millisecond {
+		t.Fatalf("timings %+v", st)
+	}
+	for i, d := range st.Latency {
+		if d < 10*time.Millisecond || d > st.Wall {
+			t.Errorf("call %d took %v of %v", i, d, st.Wall)
+		}
+	}
+
+	//a second batch adds to the same stats
+	if _, err := srv.ExecBatch(rs[:1], jrc.WithStats(&st)); err != nil {
+		t.Fatal(err)
+	}
+	if st.Calls != 4 || len(st.Latency) != 4 {
+		t.Errorf("stats after a second batch %+v", st)
+	}
+}
@@ -0,0 +1,190 @@
+package jrc
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/cfoxon/jrc/internal/json"
+)
+
+//EndpointProvider lists the addresses of a Server's nodes, as full URLs or as host:port, for Discover
+type EndpointProvider interface {
+	Endpoints(ctx context.Context) ([]string, error)
+}
+
+//EndpointProviderFunc adapts a function to an EndpointProvider
+type EndpointProviderFunc func(ctx context.Context) ([]string, error)
+
+//Endpoints implements EndpointProvider
+func (f EndpointProviderFunc) Endpoints(ctx context.Context) ([]string, error) {
+	return f(ctx)
+}
+
+//lookupSRV resolves SRV records, replaced in tests
+var lookupSRV = net.DefaultResolver.LookupSRV
+
+//SRVEndpoints lists the targets of the DNS SRV records of _Service._Proto.Name, in the order of their priority and weight
+type SRVEndpoints struct {
+	Service, Proto, Name string
+}
+
+//Endpoints implements EndpointProvider
+func (s SRVEndpoints) Endpoints(ctx context.Context) ([]string, error) {
+	_, records, err := lookupSRV(ctx, s.Service, s.Proto, s.Name)
+	if err != nil {
+		return nil, err
+	}
+	addrs := make([]string, 0, len(records))
+	for _, r := range records {
+		addrs = append(addrs, net.JoinHostPort(strings.TrimSuffix(r.Target, "."), strconv.Itoa(int(r.Port))))
+	}
+	return addrs, nil
+}
+
+//FileEndpoints reads one address per line from the file at Path, skipping blank lines and those starting with #
+//  the file is read again on every refresh, so it can be rewritten by whatever keeps the list of nodes
+type FileEndpoints struct {
+	Path string
+}
+
+//Endpoints implements EndpointProvider
+func (f FileEndpoints) Endpoints(context.Context) ([]string, error) {
+	file, err := os.Open(f.Path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	var addrs []string
+	sc := bufio.NewScanner(file)
+	for sc.Scan() {
+		if line := strings.TrimSpace(sc.Text()); line != "" && !strings.HasPrefix(line, "#") {
+			addrs = append(addrs, line)
+		}
+	}
+	return addrs, sc.Err()
+}
+
+//ConsulEndpoints lists the instances of a service registered with Consul that pass their health checks,
+//  using the agent's HTTP API; the order is Consul's, so Balance(RoundRobin) spreads calls across them
+type ConsulEndpoints struct {
+	Addr    string       //of the Consul agent, such as http://127.0.0.1:8500
+	Service string       //the name the service is registered under
+	Tag     string       //only instances with this tag, if set
+	Token   string       //ACL token, if the agent needs one
+	Client  *http.Client //http.DefaultClient when nil
+}
+
+//Endpoints implements EndpointProvider
+func (c ConsulEndpoints) Endpoints(ctx context.Context) ([]string, error) {
+	q := url.Values{"passing": {"true"}}
+	if c.Tag != "" {
+		q.Set("tag", c.Tag)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimSuffix(c.Addr, "/")+"/v1/health/service/"+url.PathEscape(c.Service)+"?"+q.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	if c.Token != "" {
+		req.Header.Set("X-Consul-Token", c.Token)
+	}
+	var entries []struct {
+		Node    struct{ Address string }
+		Service struct {
+			Address string
+			Port    int
+		}
+	}
+	if err := fetchJSON(c.Client, req, &entries); err != nil {
+		return nil, err
+	}
+	addrs := make([]string, 0, len(entries))
+	for _, e := range entries {
+		host := e.Service.Address
+		if host == "" {
+			//services registered without an address are reached at their node's
+			host = e.Node.Address
+		}
+		addrs = append(addrs, net.JoinHostPort(host, strconv.Itoa(e.Service.Port)))
+	}
+	return addrs, nil
+}
+
+//EtcdEndpoints lists the values of the keys under Prefix in etcd, each an address, using the v3 JSON gateway
+//  the addresses come in the order of their keys
+type EtcdEndpoints struct {
+	Addr   string       //of an etcd member, such as http://127.0.0.1:2379
+	Prefix string       //such as /services/rpc/
+	Client *http.Client //http.DefaultClient when nil
+}
+
+//Endpoints implements EndpointProvider
+func (e EtcdEndpoints) Endpoints(ctx context.Context) ([]string, error) {
+	body, err := json.Marshal(struct {
+		Key      []byte `json:"key"`
+		RangeEnd []byte `json:"range_end"`
+	}{[]byte(e.Prefix), prefixEnd([]byte(e.Prefix))})
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimSuffix(e.Addr, "/")+"/v3/kv/range", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", jsonContentType)
+	var resp struct {
+		Kvs []struct {
+			Value []byte `json:"value"`
+		} `json:"kvs"`
+	}
+	if err := fetchJSON(e.Client, req, &resp); err != nil {
+		return nil, err
+	}
+	addrs := make([]string, 0, len(resp.Kvs))
+	for _, kv := range resp.Kvs {
+		if addr := strings.TrimSpace(string(kv.Value)); addr != "" {
+			addrs = append(addrs, addr)
+		}
+	}
+	return addrs, nil
+}
+
+//prefixEnd returns the key just past every key starting with prefix, as etcd's range_end for a prefix
+func prefixEnd(prefix []byte) []byte {
+	end := append([]byte(nil), prefix...)
+	for i := len(end) - 1; i >= 0; i-- {
+		if end[i] < 0xff {
+			end[i]++
+			return end[:i+1]
+		}
+	}
+	//every key, as there is none after a prefix of 0xff bytes
+	return []byte{0}
+}
+
+//fetchJSON sends req with c, or http.DefaultClient, decoding the body of a 200 response into v
+func fetchJSON(c *http.Client, req *http.Request, v interface{}) error {
+	if c == nil {
+		c = http.DefaultClient
+	}
+	resp, err := c.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return &StatusError{StatusCode: resp.StatusCode, Body: b}
+	}
+	return json.Unmarshal(b, v)
+}
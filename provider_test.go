@@ -0,0 +1,95 @@
+package jrc_test
+
+import (
+	"context"
+	"encoding/base64"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cfoxon/jrc"
+)
+
+func TestConsulEndpoints(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/health/service/rpc" || r.URL.Query().Get("passing") != "true" || r.URL.Query().Get("tag") != "archive" || r.Header.Get("X-Consul-Token") != "secret" {
+			http.Error(w, "bad query "+r.URL.String(), http.StatusBadRequest)
+			return
+		}
+		w.Write([]byte(`[
+			{"Node":{"Address":"10.0.0.1"},"Service":{"Address":"","Port":8090}},
+			{"Node":{"Address":"10.0.0.2"},"Service":{"Address":"10.1.0.2","Port":8091}}
+		]`))
+	}))
+	defer ts.Close()
+	p := jrc.ConsulEndpoints{Addr: ts.URL, Service: "rpc", Tag: "archive", Token: "secret"}
+	addrs, err := p.Endpoints(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"10.0.0.1:8090", "10.1.0.2:8091"}; !reflect.DeepEqual(addrs, want) {
+		t.Errorf("got %v, want %v", addrs, want)
+	}
+	p.Token = ""
+	if _, err := p.Endpoints(context.Background()); err == nil {
+		t.Error("an error status was accepted")
+	}
+}
+
+func TestEtcdEndpoints(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		//the range covers every key under the prefix
+		want := `{"key":"` + base64.StdEncoding.EncodeToString([]byte("/rpc/")) + `","range_end":"` + base64.StdEncoding.EncodeToString([]byte("/rpc0")) + `"}`
+		if r.URL.Path != "/v3/kv/range" || string(b) != want {
+			http.Error(w, "bad range "+string(b), http.StatusBadRequest)
+			return
+		}
+		value := func(s string) string {
+			return `{"key":"","value":"` + base64.StdEncoding.EncodeToString([]byte(s)) + `"}`
+		}
+		w.Write([]byte(`{"kvs":[` + value("https://a.example/rpc") + `,` + value("10.0.0.2:8090") + `],"count":"2"}`))
+	}))
+	defer ts.Close()
+	addrs, err := jrc.EtcdEndpoints{Addr: ts.URL, Prefix: "/rpc/"}.Endpoints(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"https://a.example/rpc", "10.0.0.2:8090"}; !reflect.DeepEqual(addrs, want) {
+		t.Errorf("got %v, want %v", addrs, want)
+	}
+}
+
+func TestDiscoverFromFile(t *testing.T) {
+	nodes := make([]*httptest.Server, 2)
+	for i := range nodes {
+		name := string(rune('a' + i))
+		nodes[i] = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(`[{"jsonrpc":"2.0","id":1,"result":"` + name + `"}]`))
+		}))
+		defer nodes[i].Close()
+	}
+	path := filepath.Join(t.TempDir(), "nodes")
+	os.WriteFile(path, []byte("# archive nodes\n"+strings.TrimPrefix(nodes[0].URL, "http://")+"\n\n"), 0o600)
+	srv, err := jrc.NewServer("http://placeholder", jrc.Discover(jrc.FileEndpoints{Path: path}, 10*time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer srv.Close()
+	if res, err := srv.Call(context.Background(), "name"); err != nil || string(res) != `"a"` {
+		t.Fatalf("got %s, %v", res, err)
+	}
+
+	//a full URL is used as it is
+	os.WriteFile(path, []byte(nodes[1].URL+"\n"), 0o600)
+	time.Sleep(50 * time.Millisecond)
+	if res, err := srv.Call(context.Background(), "name"); err != nil || string(res) != `"b"` {
+		t.Fatalf("got %s, %v after the file changed", res, err)
+	}
+}
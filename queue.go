@@ -0,0 +1,324 @@
+package jrc
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/cfoxon/jrc/internal/json"
+)
+
+const (
+	queueRetryMin = time.Second //wait after the first failed drain, doubled for each one after
+	queueRetryMax = time.Minute
+	queueCompact  = 1000 //acknowledged records the log may hold before it is rewritten
+)
+
+//Queue is a durable queue of requests kept in a file and drained to a Server, for jobs that must not lose calls across restarts
+//  requests are on disk before Enqueue returns and only dropped once the Server has answered them, so a request
+//  may be sent again after a crash; the file is an append-only log of JSON lines, compacted as requests are answered
+type Queue struct {
+	srv  *Server
+	path string
+	fn   func(RpcRequest, RpcResponse)
+
+	mu      sync.Mutex
+	f       *os.File
+	seq     uint64
+	pending []queued
+	acked   int //acknowledgements in the log since it was last compacted
+	closed  bool
+	wake    chan struct{}
+}
+
+//queued is a request in the queue along with its position in the log
+type queued struct {
+	seq uint64
+	r   RpcRequest
+}
+
+//queueRecord is one line of the log, either a request or the acknowledgement of one
+type queueRecord struct {
+	Seq     uint64          `json:"seq"`
+	Ack     bool            `json:"ack,omitempty"`
+	JsonRpc string          `json:"jsonrpc,omitempty"`
+	Id      ID              `json:"id"`
+	Method  string          `json:"method,omitempty"`
+	Params  json.RawMessage `json:"params,omitempty"` //kept raw so numbers round trip exactly
+}
+
+//OpenQueue opens the queue in the file at path, creating it if needed, with the requests left in it by an earlier run pending
+//  fn is called with each request and the Server's response to it, which may carry an RpcError, from the goroutine calling Run
+//  requests without an id are given their position in the queue as their id
+func (srv *Server) OpenQueue(path string, fn func(RpcRequest, RpcResponse)) (*Queue, error) {
+	q := &Queue{srv: srv, path: path, fn: fn, wake: make(chan struct{}, 1)}
+	if err := q.load(); err != nil {
+		return nil, err
+	}
+	if err := q.compact(); err != nil {
+		return nil, err
+	}
+	return q, nil
+}
+
+//load replays the log, dropping a last line left half written by a crash
+func (q *Queue) load() error {
+	f, err := os.Open(q.path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	index := make(map[uint64]int)
+	rd := bufio.NewReader(f)
+	for {
+		line, err := rd.ReadBytes('\n')
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		var rec queueRecord
+		if err := json.Unmarshal(line, &rec); err != nil {
+			return errors.New("jrc: corrupt queue " + q.path + ": " + err.Error())
+		}
+		if rec.Seq > q.seq {
+			q.seq = rec.Seq
+		}
+		if rec.Ack {
+			if i, ok := index[rec.Seq]; ok {
+				q.pending[i].seq = 0
+			}
+			continue
+		}
+		r := RpcRequest{JsonRpc: rec.JsonRpc, Method: rec.Method}
+		r.SetID(rec.Id)
+		if len(rec.Params) > 0 {
+			r.Params = rec.Params
+		}
+		index[rec.Seq] = len(q.pending)
+		q.pending = append(q.pending, queued{seq: rec.Seq, r: r})
+	}
+	left := q.pending[:0]
+	for _, p := range q.pending {
+		if p.seq != 0 {
+			left = append(left, p)
+		}
+	}
+	q.pending = left
+	return nil
+}
+
+//compact rewrites the log with only the pending requests, the caller holding q.mu unless the queue is not yet shared
+func (q *Queue) compact() error {
+	tmp := q.path + ".tmp"
+	f, err := os.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o644)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(f)
+	for _, p := range q.pending {
+		if err = writeRecord(w, p); err != nil {
+			break
+		}
+	}
+	if err == nil {
+		err = w.Flush()
+	}
+	if err == nil {
+		err = f.Sync()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(tmp, q.path)
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	if q.f != nil {
+		q.f.Close()
+	}
+	if q.f, err = os.OpenFile(q.path, os.O_WRONLY|os.O_APPEND, 0o644); err != nil {
+		return err
+	}
+	q.acked = 0
+	return nil
+}
+
+func writeRecord(w io.Writer, p queued) error {
+	rec := queueRecord{Seq: p.seq, JsonRpc: p.r.JsonRpc, Id: p.r.ID(), Method: p.r.Method}
+	if p.r.Params != nil {
+		params, err := json.Marshal(p.r.Params)
+		if err != nil {
+			return err
+		}
+		rec.Params = params
+	}
+	b, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(b, '\n'))
+	return err
+}
+
+//Enqueue adds rs to the queue, returning once they are synced to disk
+func (q *Queue) Enqueue(rs ...RpcRequest) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.closed {
+		return ErrClosed
+	}
+	var buf bytes.Buffer
+	added := make([]queued, 0, len(rs))
+	for _, r := range rs {
+		q.seq++
+		if !r.hasID() {
+			r.Id = int(q.seq)
+		}
+		p := queued{seq: q.seq, r: r}
+		if err := writeRecord(&buf, p); err != nil {
+			return err
+		}
+		added = append(added, p)
+	}
+	if _, err := q.f.Write(buf.Bytes()); err != nil {
+		return err
+	}
+	if err := q.f.Sync(); err != nil {
+		return err
+	}
+	q.pending = append(q.pending, added...)
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+//Len returns the number of requests waiting for a response
+func (q *Queue) Len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.pending)
+}
+
+//Run drains the queue to the Server until ctx is done, returning ctx.Err(); only one Run should be active at a time
+//  requests go out in batches in the order they were queued, and those left unanswered by a failed call are
+//  sent again after a delay growing from a second to a minute
+func (q *Queue) Run(ctx context.Context) error {
+	delay := queueRetryMin
+	for {
+		batch := q.next()
+		if len(batch) == 0 {
+			select {
+			case <-q.wake:
+				continue
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+		rs := make(RPCRequests, len(batch))
+		for i := range batch {
+			rs[i] = &batch[i].r
+		}
+		resps, err := q.srv.ExecBatchContext(ctx, rs)
+		answered, aerr := q.ack(batch, Correlate(rs, resps))
+		if aerr != nil {
+			return aerr
+		}
+		if err == nil && answered == len(batch) {
+			delay = queueRetryMin
+			continue
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if err == nil {
+			err = errors.New("jrc: no response to some queued requests")
+		}
+		q.srv.log(LogWarn, "jrc: queue drain failed", "error", err, "retry", delay)
+		if !sleep(ctx, delay) {
+			return ctx.Err()
+		}
+		if delay *= 2; delay > queueRetryMax {
+			delay = queueRetryMax
+		}
+	}
+}
+
+//next returns the oldest pending requests, as many as the Server sends at once
+func (q *Queue) next() []queued {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	n := q.srv.batchSize() * q.srv.conn
+	if n < 1 || n > len(q.pending) {
+		n = len(q.pending)
+	}
+	return append([]queued(nil), q.pending[:n]...)
+}
+
+//ack hands the responses to fn and drops the answered requests from the queue, returning how many there were
+func (q *Queue) ack(batch []queued, resps []*RpcResponse) (int, error) {
+	done := make(map[uint64]bool)
+	var buf bytes.Buffer
+	for i, resp := range resps {
+		if resp == nil {
+			continue
+		}
+		if q.fn != nil {
+			q.fn(batch[i].r, *resp)
+		}
+		done[batch[i].seq] = true
+		b, _ := json.Marshal(queueRecord{Seq: batch[i].seq, Ack: true})
+		buf.Write(append(b, '\n'))
+	}
+	if len(done) == 0 {
+		return 0, nil
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.closed {
+		return 0, ErrClosed
+	}
+	left := q.pending[:0]
+	for _, p := range q.pending {
+		if !done[p.seq] {
+			left = append(left, p)
+		}
+	}
+	q.pending = left
+	if _, err := q.f.Write(buf.Bytes()); err != nil {
+		return 0, err
+	}
+	if q.acked += len(done); q.acked >= queueCompact {
+		return len(done), q.compact()
+	}
+	return len(done), nil
+}
+
+//Close compacts and closes the file, requests still pending are kept for the next OpenQueue
+func (q *Queue) Close() error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.closed {
+		return nil
+	}
+	q.closed = true
+	err := q.compact()
+	if cerr := q.f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
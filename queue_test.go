@@ -0,0 +1,119 @@
+package jrc_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cfoxon/jrc"
+	"github.com/cfoxon/jrc/jrctest"
+)
+
+//drain runs q until it is empty, failing the test if that takes too long
+func drain(t *testing.T, q *jrc.Queue) {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	done := make(chan struct{})
+	go func() {
+		q.Run(ctx)
+		close(done)
+	}()
+	for q.Len() > 0 && ctx.Err() == nil {
+		time.Sleep(10 * time.Millisecond)
+	}
+	cancel()
+	<-done
+	if q.Len() > 0 {
+		t.Fatalf("%d requests still queued", q.Len())
+	}
+}
+
+func TestQueueReplaysAcrossRestarts(t *testing.T) {
+	ts := jrctest.NewServer()
+	defer ts.Close()
+	ts.Echo("store")
+	srv, _ := ts.Client(jrc.MaxBatch(2))
+	defer srv.Close()
+	path := filepath.Join(t.TempDir(), "queue.log")
+
+	q, err := srv.OpenQueue(path, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 5; i++ {
+		if err := q.Enqueue(jrc.RpcRequest{JsonRpc: "2.0", Method: "store", Params: []int{i}}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := q.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if ts.Posts() != 0 {
+		t.Fatal("requests sent before Run")
+	}
+
+	//a crash while appending leaves half a line, which is dropped
+	f, _ := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0)
+	f.WriteString(`{"seq":6,"jsonrpc":"2.0","meth`)
+	f.Close()
+
+	var mu sync.Mutex
+	var got []string
+	q, err = srv.OpenQueue(path, func(r jrc.RpcRequest, resp jrc.RpcResponse) {
+		mu.Lock()
+		defer mu.Unlock()
+		got = append(got, r.ID().String()+"="+string(resp.Result))
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if q.Len() != 5 {
+		t.Fatalf("%d requests replayed, want 5", q.Len())
+	}
+	drain(t, q)
+	q.Close()
+
+	//requests go out in the order queued, numbered by their position as they had no id
+	want := "1=[0] 2=[1] 3=[2] 4=[3] 5=[4]"
+	if strings.Join(got, " ") != want {
+		t.Errorf("got %v, want %s", got, want)
+	}
+	if n := ts.Posts(); n != 3 {
+		t.Errorf("%d calls made, want 3 of at most MaxBatch requests", n)
+	}
+
+	//answered requests are not replayed, and the log has been compacted
+	q, _ = srv.OpenQueue(path, nil)
+	defer q.Close()
+	if q.Len() != 0 {
+		t.Errorf("%d answered requests replayed", q.Len())
+	}
+	if info, _ := os.Stat(path); info.Size() != 0 {
+		t.Errorf("log of an empty queue is %d bytes", info.Size())
+	}
+}
+
+func TestQueueRetriesFailedCalls(t *testing.T) {
+	ts := jrctest.NewServer()
+	defer ts.Close()
+	ts.Echo("store")
+	ts.FailNext(502)
+	srv, _ := ts.Client(jrc.MaxBatch(0))
+	defer srv.Close()
+
+	q, err := srv.OpenQueue(filepath.Join(t.TempDir(), "queue.log"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer q.Close()
+	q.Enqueue(jrc.RpcRequest{JsonRpc: "2.0", Id: 7, Method: "store"}, jrc.RpcRequest{JsonRpc: "2.0", Method: "store"})
+	drain(t, q)
+	if n := ts.Posts(); n != 2 {
+		t.Errorf("%d calls made, want the failed one and its retry", n)
+	}
+}
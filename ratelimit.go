@@ -0,0 +1,69 @@
+package jrc
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+//limiter is a token bucket shared by all of a Server's workers
+type limiter struct {
+	mu     sync.Mutex
+	rate   float64 //tokens added per second
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+func newLimiter(perSecond float64, burst int) *limiter {
+	if burst < 1 {
+		burst = 1
+	}
+	return &limiter{rate: perSecond, burst: float64(burst), tokens: float64(burst), last: time.Now()}
+}
+
+//wait takes a token, blocking until one is available or ctx is done
+func (l *limiter) wait(ctx context.Context) error {
+	l.mu.Lock()
+	now := time.Now()
+	l.tokens += now.Sub(l.last).Seconds() * l.rate
+	if l.tokens > l.burst {
+		l.tokens = l.burst
+	}
+	l.last = now
+	l.tokens--
+	var d time.Duration
+	if l.tokens < 0 {
+		d = time.Duration(-l.tokens / l.rate * float64(time.Second))
+	}
+	l.mu.Unlock()
+	if d == 0 || sleep(ctx, d) {
+		return nil
+	}
+	//give back the token reserved for the call that is no longer waiting
+	l.mu.Lock()
+	l.tokens++
+	l.mu.Unlock()
+	return ctx.Err()
+}
+
+func (srv *Server) setRateLimit(perSecond float64, burst int) error {
+	if perSecond < 0 {
+		return errors.New("jrc: rate limit cannot be negative")
+	}
+	if perSecond == 0 {
+		srv.limit = nil
+		return nil
+	}
+	srv.limit = newLimiter(perSecond, burst)
+	return nil
+}
+
+//RateLimit caps the HTTP requests sent by all workers together at perSecond, allowing bursts of up to burst requests
+//  retries and hedged requests count against the limit; zero removes it
+func RateLimit(perSecond float64, burst int) func(server *Server) error {
+	return func(srv *Server) error {
+		return srv.setRateLimit(perSecond, burst)
+	}
+}
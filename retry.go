@@ -0,0 +1,119 @@
+package jrc
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+//RetryPolicy controls how transport-level failures are retried
+//  the zero value disables retries
+type RetryPolicy struct {
+	Max      int           //retries after the first attempt
+	Base     time.Duration //delay before the first retry, doubled for each one after
+	MaxDelay time.Duration //cap on the delay between attempts, zero for no cap
+	Jitter   float64       //fraction of each delay to randomize, from 0 to 1
+}
+
+var (
+	jitterMu   sync.Mutex
+	jitterRand = rand.New(rand.NewSource(time.Now().UnixNano()))
+)
+
+//delay returns how long to wait before retry number attempt (starting at 0)
+func (p RetryPolicy) delay(attempt int) time.Duration {
+	d := p.Base
+	for i := 0; i < attempt && (p.MaxDelay == 0 || d < p.MaxDelay); i++ {
+		d *= 2
+	}
+	if p.MaxDelay > 0 && d > p.MaxDelay {
+		d = p.MaxDelay
+	}
+	if p.Jitter > 0 && d > 0 {
+		jitterMu.Lock()
+		f := jitterRand.Float64()*2 - 1
+		jitterMu.Unlock()
+		d += time.Duration(f * p.Jitter * float64(d))
+	}
+	return d
+}
+
+func (p RetryPolicy) check() error {
+	if p.Max < 0 || p.Base < 0 || p.MaxDelay < 0 {
+		return errors.New("jrc: retry values cannot be negative")
+	}
+	if p.Jitter < 0 || p.Jitter > 1 {
+		return errors.New("jrc: retry jitter must be between 0 and 1")
+	}
+	return nil
+}
+
+func (srv *Server) setRetry(p RetryPolicy) error {
+	if err := p.check(); err != nil {
+		return err
+	}
+	srv.retry = p
+	return nil
+}
+
+//Retry sets the policy used to retry requests that fail at the transport level
+func Retry(p RetryPolicy) func(server *Server) error {
+	return func(srv *Server) error {
+		return srv.setRetry(p)
+	}
+}
+
+//roundTrip sends c to the Server, retrying transport errors according to the RetryPolicy of its profile or the Server's
+func (srv *Server) roundTrip(c *call) ([]byte, error) {
+	p := c.msg.profile.retry(srv.retry)
+	b, err := srv.attempt(c)
+	for attempt := 0; retryable(err) && attempt < p.Max; attempt++ {
+		d := p.delay(attempt)
+		srv.log(LogWarn, "jrc: retrying", "attempt", attempt+1, "delay", d, "error", err)
+		if !sleep(c.ctx, d) {
+			return nil, err
+		}
+		c.msg.tally.retried()
+		b, err = srv.attempt(c)
+	}
+	if se, ok := err.(*StatusError); ok && len(se.Body) > 0 {
+		return se.Body, nil
+	}
+	return b, err
+}
+
+//retryable reports whether err is a transport failure worth trying again
+func retryable(err error) bool {
+	if err == nil || err == ErrCircuitOpen || err == ErrResponseTooLarge || err == errNoProfileEndpoint {
+		return false
+	}
+	_, status := err.(*StatusError)
+	return !status
+}
+
+//attempt makes a single try at sending c, failing over between endpoints, unless the circuit breaker is open
+func (srv *Server) attempt(c *call) ([]byte, error) {
+	if srv.breaker == nil {
+		return srv.failover(c)
+	}
+	if err := srv.breaker.allow(); err != nil {
+		return nil, err
+	}
+	b, err := srv.failover(c)
+	srv.breaker.record(err)
+	return b, err
+}
+
+//sleep waits for d, returning false if ctx is done first
+func sleep(ctx context.Context, d time.Duration) bool {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
@@ -0,0 +1,149 @@
+package server
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/cfoxon/jrc"
+	"github.com/cfoxon/jrc/internal/json"
+)
+
+//Error codes of the built in middleware, in the range left to servers
+const (
+	CodeUnauthorized  = -32001
+	CodeLimitExceeded = -32005
+)
+
+//Middleware wraps a Handler to check calls, answer them itself without calling next, or watch the results
+type Middleware func(next Handler) Handler
+
+//Use adds middleware around the handler of every method, the first one given being the outermost
+func Use(mw ...Middleware) func(*Server) error {
+	return func(s *Server) error {
+		s.middleware = append(s.middleware, mw...)
+		return nil
+	}
+}
+
+//chain wraps h in the Server's middleware
+func (s *Server) chain(h Handler) Handler {
+	for i := len(s.middleware) - 1; i >= 0; i-- {
+		h = s.middleware[i](h)
+	}
+	return h
+}
+
+//Only applies mw to calls of the given methods, the others going straight to the next Handler
+func Only(mw Middleware, methods ...string) Middleware {
+	set := make(map[string]bool, len(methods))
+	for _, m := range methods {
+		set[m] = true
+	}
+	return func(next Handler) Handler {
+		wrapped := mw(next)
+		return func(ctx context.Context, params json.RawMessage) (interface{}, error) {
+			if set[Method(ctx)] {
+				return wrapped(ctx, params)
+			}
+			return next(ctx, params)
+		}
+	}
+}
+
+type ctxKey int
+
+const (
+	methodKey ctxKey = iota
+	headerKey
+)
+
+//Method returns the method being called, for middleware
+func Method(ctx context.Context) string {
+	m, _ := ctx.Value(methodKey).(string)
+	return m
+}
+
+//Header returns the value of a header of the HTTP request carrying the call, empty when it came another way
+func Header(ctx context.Context, name string) string {
+	if get, ok := ctx.Value(headerKey).(func(string) string); ok {
+		return get(name)
+	}
+	return ""
+}
+
+//Log is a Middleware sending every call to l, at jrc.LogDebug or jrc.LogWarn for calls that fail, up to verbosity
+func Log(l jrc.Logger, verbosity jrc.LogLevel) Middleware {
+	return func(next Handler) Handler {
+		return func(ctx context.Context, params json.RawMessage) (interface{}, error) {
+			start := time.Now()
+			result, err := next(ctx, params)
+			switch {
+			case err != nil && verbosity >= jrc.LogWarn:
+				l.Log(jrc.LogWarn, "server: call failed", "method", Method(ctx), "took", time.Since(start), "error", err)
+			case err == nil && verbosity >= jrc.LogDebug:
+				l.Log(jrc.LogDebug, "server: call", "method", Method(ctx), "took", time.Since(start))
+			}
+			return result, err
+		}
+	}
+}
+
+//Auth is a Middleware passing the Authorization header of each call to check, refusing the call if it returns an error
+//  an error of type *jrc.RpcError is sent as it is, any other as CodeUnauthorized
+func Auth(check func(ctx context.Context, authorization string) error) Middleware {
+	return func(next Handler) Handler {
+		return func(ctx context.Context, params json.RawMessage) (interface{}, error) {
+			if err := check(ctx, Header(ctx, "Authorization")); err != nil {
+				if rpcErr, ok := err.(*jrc.RpcError); ok {
+					return nil, rpcErr
+				}
+				return nil, &jrc.RpcError{Code: CodeUnauthorized, Message: "Unauthorized"}
+			}
+			return next(ctx, params)
+		}
+	}
+}
+
+//bucket is a token bucket shared by the calls of every method a RateLimit applies to
+type bucket struct {
+	mu     sync.Mutex
+	rate   float64 //tokens added per second
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+//take takes a token, false if there is none left
+func (b *bucket) take() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	now := time.Now()
+	b.tokens += now.Sub(b.last).Seconds() * b.rate
+	if b.tokens > b.burst {
+		b.tokens = b.burst
+	}
+	b.last = now
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+//RateLimit is a Middleware answering calls beyond perSecond, after a burst of up to burst calls, with CodeLimitExceeded
+//  the limit is shared by every call it applies to, so use Only for a limit on some methods
+func RateLimit(perSecond float64, burst int) Middleware {
+	if burst < 1 {
+		burst = 1
+	}
+	b := &bucket{rate: perSecond, burst: float64(burst), tokens: float64(burst), last: time.Now()}
+	return func(next Handler) Handler {
+		return func(ctx context.Context, params json.RawMessage) (interface{}, error) {
+			if !b.take() {
+				return nil, &jrc.RpcError{Code: CodeLimitExceeded, Message: "rate limit exceeded"}
+			}
+			return next(ctx, params)
+		}
+	}
+}
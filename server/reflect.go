@@ -0,0 +1,131 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"reflect"
+	"strconv"
+
+	"github.com/cfoxon/jrc"
+	"github.com/cfoxon/jrc/internal/json"
+)
+
+var (
+	contextType     = reflect.TypeOf((*context.Context)(nil)).Elem()
+	errorType       = reflect.TypeOf((*error)(nil)).Elem()
+	unmarshalerType = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+)
+
+//Func makes a Handler of f, with the params decoded into a P and the result encoded from an R
+//  params sent as an array fill the exported fields of a struct P in order; params that can't be decoded are
+//  answered with jrc.CodeInvalidParams
+func Func[P, R any](f func(ctx context.Context, params P) (R, error)) Handler {
+	return func(ctx context.Context, raw json.RawMessage) (interface{}, error) {
+		var p P
+		if err := decodeParams(raw, &p); err != nil {
+			return nil, invalidParams(err)
+		}
+		return f(ctx, p)
+	}
+}
+
+//Register adds the exported methods of rcvr as namespace.Name, or just Name if namespace is empty
+//  methods must be of the form Name(ctx context.Context, params P) (R, error), or Name(params P, reply *R) error
+//  as in net/rpc; others are left out, and params are decoded as by Func
+func (s *Server) Register(namespace string, rcvr interface{}) error {
+	v := reflect.ValueOf(rcvr)
+	t := v.Type()
+	n := 0
+	for i := 0; i < t.NumMethod(); i++ {
+		m := t.Method(i)
+		if m.PkgPath != "" {
+			continue
+		}
+		h := methodHandler(v.Method(i))
+		if h == nil {
+			continue
+		}
+		name := m.Name
+		if namespace != "" {
+			name = namespace + "." + name
+		}
+		s.Handle(name, h)
+		n++
+	}
+	if n == 0 {
+		return errors.New("server: " + t.String() + " has no methods of a form Register accepts")
+	}
+	return nil
+}
+
+//methodHandler makes a Handler of the bound method f, nil if it is of neither form Register accepts
+func methodHandler(f reflect.Value) Handler {
+	ft := f.Type()
+	switch {
+	case ft.NumIn() == 2 && ft.In(0) == contextType && ft.NumOut() == 2 && ft.Out(1) == errorType:
+		pt := ft.In(1)
+		return func(ctx context.Context, raw json.RawMessage) (interface{}, error) {
+			p := reflect.New(pt)
+			if err := decodeParams(raw, p.Interface()); err != nil {
+				return nil, invalidParams(err)
+			}
+			out := f.Call([]reflect.Value{reflect.ValueOf(ctx), p.Elem()})
+			if err, _ := out[1].Interface().(error); err != nil {
+				return nil, err
+			}
+			return out[0].Interface(), nil
+		}
+	case ft.NumIn() == 2 && ft.In(1).Kind() == reflect.Ptr && ft.NumOut() == 1 && ft.Out(0) == errorType:
+		pt, rt := ft.In(0), ft.In(1).Elem()
+		return func(ctx context.Context, raw json.RawMessage) (interface{}, error) {
+			p := reflect.New(pt)
+			if err := decodeParams(raw, p.Interface()); err != nil {
+				return nil, invalidParams(err)
+			}
+			reply := reflect.New(rt)
+			out := f.Call([]reflect.Value{p.Elem(), reply})
+			if err, _ := out[0].Interface().(error); err != nil {
+				return nil, err
+			}
+			return reply.Interface(), nil
+		}
+	}
+	return nil
+}
+
+//decodeParams decodes raw into the value p points to, leaving it zero when there are no params
+//  an array is decoded into the exported fields of a struct one by one, for servers taking positional params
+func decodeParams(raw json.RawMessage, p interface{}) error {
+	raw = bytes.TrimSpace(raw)
+	if len(raw) == 0 || string(raw) == "null" {
+		return nil
+	}
+	v := reflect.ValueOf(p).Elem()
+	if raw[0] != '[' || v.Kind() != reflect.Struct || v.Addr().Type().Implements(unmarshalerType) {
+		return json.Unmarshal(raw, p)
+	}
+	var elems []json.RawMessage
+	if err := json.Unmarshal(raw, &elems); err != nil {
+		return err
+	}
+	var fields []reflect.Value
+	for i := 0; i < v.NumField(); i++ {
+		if f := v.Type().Field(i); f.PkgPath == "" && f.Tag.Get("json") != "-" {
+			fields = append(fields, v.Field(i))
+		}
+	}
+	if len(elems) > len(fields) {
+		return errors.New(strconv.Itoa(len(elems)) + " params given, at most " + strconv.Itoa(len(fields)) + " expected")
+	}
+	for i, e := range elems {
+		if err := json.Unmarshal(e, fields[i].Addr().Interface()); err != nil {
+			return errors.New("param " + strconv.Itoa(i) + ": " + err.Error())
+		}
+	}
+	return nil
+}
+
+func invalidParams(err error) *jrc.RpcError {
+	return &jrc.RpcError{Code: jrc.CodeInvalidParams, Message: "Invalid params: " + err.Error()}
+}
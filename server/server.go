@@ -0,0 +1,290 @@
+//Package server answers JSON RPC 2.0 calls over HTTP with Go functions registered by method name
+//  it shares its types with jrc, so a service and its clients can be built from the same definitions
+package server
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+
+	"github.com/cfoxon/jrc"
+	"github.com/cfoxon/jrc/internal/json"
+	"github.com/valyala/fasthttp"
+)
+
+//Handler answers one call of a method with its result, or an error
+//  an error of type *jrc.RpcError is sent as it is, any other as jrc.CodeInternalError with the error's text as the message,
+//  and a panic is answered with jrc.CodeInternalError too
+type Handler func(ctx context.Context, params json.RawMessage) (interface{}, error)
+
+//Server is a registry of methods and the HTTP handlers serving them
+type Server struct {
+	mu         sync.RWMutex
+	methods    map[string]Handler
+	middleware []Middleware
+	maxBody    int
+	workers    int //calls of a batch run at once
+}
+
+//request is a call as decoded from the body, Id being absent for a notification
+type request struct {
+	JsonRpc string          `json:"jsonrpc"`
+	Id      json.RawMessage `json:"id"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params"`
+}
+
+//New creates a Server with no methods
+func New(options ...func(*Server) error) (*Server, error) {
+	s := &Server{methods: make(map[string]Handler), workers: 8}
+	for _, opt := range options {
+		if err := opt(s); err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+//MaxBodySize limits the size of request bodies, larger ones are refused with HTTP status 413
+func MaxBodySize(n int) func(*Server) error {
+	return func(s *Server) error {
+		if n < 0 {
+			return errors.New("server: negative MaxBodySize")
+		}
+		s.maxBody = n
+		return nil
+	}
+}
+
+//Concurrency sets how many calls of a batch are run at once, 8 by default and 1 to run them in order
+func Concurrency(n int) func(*Server) error {
+	return func(s *Server) error {
+		if n < 1 {
+			return errors.New("server: Concurrency must be at least 1")
+		}
+		s.workers = n
+		return nil
+	}
+}
+
+//Handle registers h, wrapped in the middleware given by Use, to answer calls of method, replacing any handler it had
+func (s *Server) Handle(method string, h Handler) {
+	h = s.chain(h)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.methods[method] = h
+}
+
+//handler returns the Handler of method, nil if it has none
+func (s *Server) handler(method string) Handler {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.methods[method]
+}
+
+//Respond answers the JSON RPC request or batch in body, returning the response body or nil when there is nothing to answer
+//  it is what the HTTP handlers call, for serving over other transports
+func (s *Server) Respond(ctx context.Context, body []byte) []byte {
+	body = bytes.TrimSpace(body)
+	if len(body) > 0 && body[0] == '[' {
+		return s.batch(ctx, body)
+	}
+	var q request
+	if err := json.Unmarshal(body, &q); err != nil {
+		return marshal(failure(nil, jrc.CodeParseError, "Parse error"))
+	}
+	resp := s.call(ctx, &q)
+	if resp == nil {
+		return nil
+	}
+	return marshal(resp)
+}
+
+//batch answers the calls of a batch, running up to s.workers at once
+//  responses are in the order of the calls, leaving out notifications, and items that are not requests get an error each
+func (s *Server) batch(ctx context.Context, body []byte) []byte {
+	var items []json.RawMessage
+	if err := json.Unmarshal(body, &items); err != nil {
+		return marshal(failure(nil, jrc.CodeParseError, "Parse error"))
+	}
+	if len(items) == 0 {
+		return marshal(failure(nil, jrc.CodeInvalidRequest, "Invalid Request"))
+	}
+	resps := make([]*jrc.RpcResponse, len(items))
+	sem := make(chan struct{}, s.workers)
+	var wg sync.WaitGroup
+	for i, item := range items {
+		q := &request{}
+		if item = bytes.TrimSpace(item); len(item) == 0 || item[0] != '{' || json.Unmarshal(item, q) != nil {
+			resps[i] = failure(nil, jrc.CodeInvalidRequest, "Invalid Request")
+			continue
+		}
+		sem <- struct{}{}
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			resps[i] = s.call(ctx, q)
+			<-sem
+		}(i)
+	}
+	wg.Wait()
+	answered := resps[:0]
+	for _, resp := range resps {
+		if resp != nil {
+			answered = append(answered, resp)
+		}
+	}
+	if len(answered) == 0 {
+		return nil
+	}
+	return marshal(answered)
+}
+
+//call runs the handler of q, returning nil for a notification
+func (s *Server) call(ctx context.Context, q *request) *jrc.RpcResponse {
+	var id jrc.ID
+	if q.Id != nil && json.Unmarshal(q.Id, &id) != nil {
+		return failure(nil, jrc.CodeInvalidRequest, "Invalid Request")
+	}
+	//notifications are never answered, even when they are invalid
+	notify := q.Id == nil
+	resp := s.answer(ctx, q, id)
+	if notify {
+		return nil
+	}
+	return resp
+}
+
+//answer validates q and runs its method's handler
+func (s *Server) answer(ctx context.Context, q *request, id jrc.ID) *jrc.RpcResponse {
+	if q.JsonRpc != "2.0" || q.Method == "" || !structured(q.Params) {
+		return failure(&id, jrc.CodeInvalidRequest, "Invalid Request")
+	}
+	h := s.handler(q.Method)
+	if h == nil {
+		return failure(&id, jrc.CodeMethodNotFound, "Method not found")
+	}
+	result, err := run(context.WithValue(ctx, methodKey, q.Method), h, q.Params)
+	var rpcErr *jrc.RpcError
+	switch {
+	case errors.As(err, &rpcErr):
+		return &jrc.RpcResponse{JSONRPC: "2.0", Error: rpcErr, ID: id}
+	case err != nil:
+		return failure(&id, jrc.CodeInternalError, err.Error())
+	}
+	b, err := json.Marshal(result)
+	if err != nil {
+		return failure(&id, jrc.CodeInternalError, err.Error())
+	}
+	return &jrc.RpcResponse{JSONRPC: "2.0", Result: b, ID: id}
+}
+
+//run calls h, turning a panic into an error so one bad call can't end the process, as it would on a batch's goroutine
+func run(ctx context.Context, h Handler, params json.RawMessage) (result interface{}, err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			result, err = nil, &jrc.RpcError{Code: jrc.CodeInternalError, Message: fmt.Sprint("panic: ", p)}
+		}
+	}()
+	return h(ctx, params)
+}
+
+//structured reports whether params are absent, null, an array or an object, as the spec allows
+func structured(params json.RawMessage) bool {
+	params = bytes.TrimSpace(params)
+	return len(params) == 0 || params[0] == '[' || params[0] == '{' || string(params) == "null"
+}
+
+//failure builds an error response, with a null id when id is nil
+func failure(id *jrc.ID, code int, message string) *jrc.RpcResponse {
+	resp := &jrc.RpcResponse{JSONRPC: "2.0", Error: &jrc.RpcError{Code: code, Message: message}}
+	if id != nil {
+		resp.ID = *id
+	}
+	return resp
+}
+
+func marshal(v interface{}) []byte {
+	b, err := json.Marshal(v)
+	if err != nil {
+		b, _ = json.Marshal(failure(nil, jrc.CodeInternalError, err.Error()))
+	}
+	return b
+}
+
+//ServeHTTP implements http.Handler, answering POST requests with a body that may be gzipped
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	rd := io.Reader(r.Body)
+	switch r.Header.Get("Content-Encoding") {
+	case "", "identity":
+	case "gzip":
+		zr, err := gzip.NewReader(r.Body)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		defer zr.Close()
+		rd = zr
+	default:
+		w.WriteHeader(http.StatusUnsupportedMediaType)
+		return
+	}
+	if s.maxBody > 0 {
+		rd = io.LimitReader(rd, int64(s.maxBody)+1)
+	}
+	body, err := io.ReadAll(rd)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	if s.maxBody > 0 && len(body) > s.maxBody {
+		w.WriteHeader(http.StatusRequestEntityTooLarge)
+		return
+	}
+	resp := s.Respond(context.WithValue(r.Context(), headerKey, r.Header.Get), body)
+	if resp == nil {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(resp)
+}
+
+//HandleFastHTTP is a fasthttp.RequestHandler answering POST requests, e.g. fasthttp.ListenAndServe(addr, s.HandleFastHTTP)
+//  bodies may be compressed with gzip, deflate or br, and the fasthttp.Server's MaxRequestBodySize applies as well as MaxBodySize
+func (s *Server) HandleFastHTTP(ctx *fasthttp.RequestCtx) {
+	if !ctx.IsPost() {
+		ctx.Response.Header.Set("Allow", fasthttp.MethodPost)
+		ctx.SetStatusCode(fasthttp.StatusMethodNotAllowed)
+		return
+	}
+	body, err := ctx.Request.BodyUncompressed()
+	if err != nil {
+		ctx.SetStatusCode(fasthttp.StatusBadRequest)
+		return
+	}
+	if s.maxBody > 0 && len(body) > s.maxBody {
+		ctx.SetStatusCode(fasthttp.StatusRequestEntityTooLarge)
+		return
+	}
+	header := func(name string) string {
+		return string(ctx.Request.Header.Peek(name))
+	}
+	resp := s.Respond(context.WithValue(ctx, headerKey, header), body)
+	if resp == nil {
+		ctx.SetStatusCode(fasthttp.StatusNoContent)
+		return
+	}
+	ctx.SetContentType("application/json")
+	ctx.SetBody(resp)
+}
@@ -0,0 +1,137 @@
+package server_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/cfoxon/jrc"
+	"github.com/cfoxon/jrc/server"
+	"github.com/goccy/go-json"
+)
+
+func newServer(t *testing.T, options ...func(*server.Server) error) *server.Server {
+	t.Helper()
+	s, err := server.New(options...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Handle("echo", func(ctx context.Context, params json.RawMessage) (interface{}, error) {
+		return params, nil
+	})
+	s.Handle("panic", func(context.Context, json.RawMessage) (interface{}, error) {
+		panic("out of cheese")
+	})
+	return s
+}
+
+func TestBatch(t *testing.T) {
+	s := newServer(t)
+	body := `[
+		{"jsonrpc":"2.0","id":1,"method":"echo","params":[1]},
+		{"jsonrpc":"2.0","method":"echo","params":[2]},
+		42,
+		{"jsonrpc":"2.0","id":"x","method":"missing"},
+		{"jsonrpc":"2.0","id":3,"method":"echo","params":[3]}
+	]`
+	var resps []jrc.RpcResponse
+	if err := json.Unmarshal(s.Respond(context.Background(), []byte(body)), &resps); err != nil {
+		t.Fatal(err)
+	}
+	//the calls are answered in order, leaving out the notification
+	if len(resps) != 4 {
+		t.Fatalf("got %d responses, want 4: %v", len(resps), resps)
+	}
+	if resps[0].ID != jrc.IntID(1) || string(resps[0].Result) != "[1]" {
+		t.Errorf("first response %+v", resps[0])
+	}
+	if !resps[1].ID.IsNull() || resps[1].Error == nil || resps[1].Error.Code != jrc.CodeInvalidRequest {
+		t.Errorf("a batch item that isn't a request got %+v", resps[1])
+	}
+	if resps[2].ID != jrc.StringID("x") || resps[2].Error == nil || resps[2].Error.Code != jrc.CodeMethodNotFound {
+		t.Errorf("call of a missing method got %+v", resps[2])
+	}
+	if resps[3].ID != jrc.IntID(3) || string(resps[3].Result) != "[3]" {
+		t.Errorf("last response %+v", resps[3])
+	}
+}
+
+func TestBatchEdgeCases(t *testing.T) {
+	s := newServer(t)
+	for body, want := range map[string]string{
+		`[]`: `"code":-32600`,
+		`[{"jsonrpc":"2.0","method":"echo"},{"jsonrpc":"2.0","method":"echo"}]`: "",
+		`[{"jsonrpc":"2.0","id":1,"method":"echo"`:                              `"code":-32700`,
+	} {
+		got := string(s.Respond(context.Background(), []byte(body)))
+		if want == "" && got != "" || !strings.Contains(got, want) {
+			t.Errorf("%s answered with %q, want %q", body, got, want)
+		}
+	}
+}
+
+func TestBatchRunsConcurrently(t *testing.T) {
+	s, _ := server.New(server.Concurrency(3))
+	var running, most int32
+	s.Handle("work", func(context.Context, json.RawMessage) (interface{}, error) {
+		n := atomic.AddInt32(&running, 1)
+		for {
+			m := atomic.LoadInt32(&most)
+			if n <= m || atomic.CompareAndSwapInt32(&most, m, n) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+		atomic.AddInt32(&running, -1)
+		return true, nil
+	})
+	var calls []string
+	for i := 0; i < 9; i++ {
+		calls = append(calls, `{"jsonrpc":"2.0","id":1,"method":"work"}`)
+	}
+	s.Respond(context.Background(), []byte("["+strings.Join(calls, ",")+"]"))
+	if most != 3 {
+		t.Errorf("%d calls ran at once, want 3", most)
+	}
+}
+
+func TestPanicInBatchIsAnswered(t *testing.T) {
+	s := newServer(t)
+	body := `[{"jsonrpc":"2.0","id":1,"method":"panic"},{"jsonrpc":"2.0","id":2,"method":"echo","params":[2]}]`
+	var resps []jrc.RpcResponse
+	json.Unmarshal(s.Respond(context.Background(), []byte(body)), &resps)
+	if len(resps) != 2 || resps[0].Error == nil || resps[0].Error.Code != jrc.CodeInternalError || string(resps[1].Result) != "[2]" {
+		t.Fatalf("got %+v", resps)
+	}
+}
+
+func TestServeHTTPWithClient(t *testing.T) {
+	s := newServer(t)
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+	c, _ := jrc.NewServer(ts.URL, jrc.CompressRequests(1))
+	defer c.Close()
+	rs := jrc.RPCRequests{
+		{JsonRpc: "2.0", Id: 1, Method: "echo", Params: []int{1}},
+		{JsonRpc: "2.0", Id: 2, Method: "echo", Params: map[string]int{"a": 2}},
+	}
+	resps, err := c.ExecBatch(rs)
+	if err != nil || len(resps) != 2 {
+		t.Fatal(resps, err)
+	}
+	if err := c.Notify(jrc.RpcNotification{JsonRpc: "2.0", Method: "echo"}); err != nil {
+		t.Fatal(err)
+	}
+	resp, err := http.Get(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusMethodNotAllowed {
+		t.Errorf("GET answered with %d", resp.StatusCode)
+	}
+}
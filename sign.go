@@ -0,0 +1,35 @@
+package jrc
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"hash"
+	"net/http"
+)
+
+//SignHMAC returns middleware that signs the body of every HTTP call with an HMAC keyed with key,
+//  setting it hex encoded on the header named header; newHash picks the algorithm, e.g. sha512.New, nil for SHA-256
+//  the signature covers the body as sent, so it is computed after compression, or for a ReadOverGET call,
+//  which has no body, the encoded query carrying it
+func SignHMAC(key []byte, header string, newHash func() hash.Hash) Middleware {
+	if newHash == nil {
+		newHash = sha256.New
+	}
+	return func(next Handler) Handler {
+		return func(ctx context.Context, m *Message) ([]byte, error) {
+			mac := hmac.New(newHash, key)
+			if m.Query != nil {
+				mac.Write([]byte(m.Query.Encode()))
+			} else {
+				mac.Write(m.Body)
+			}
+			if m.Header == nil {
+				m.Header = make(http.Header)
+			}
+			m.Header.Set(header, hex.EncodeToString(mac.Sum(nil)))
+			return next(ctx, m)
+		}
+	}
+}
@@ -0,0 +1,148 @@
+package jrc
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+//AWSCredentials are the keys SigV4 signs requests with
+type AWSCredentials struct {
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string //for temporary credentials, empty otherwise
+}
+
+//SigV4 signs every HTTP call with AWS Signature Version 4 using fixed credentials,
+//  for JSON RPC services behind API Gateway or Amazon Managed Blockchain; service is e.g. "execute-api" or "managedblockchain"
+func SigV4(region, service string, creds AWSCredentials) func(server *Server) error {
+	return SigV4Func(region, service, func(context.Context) (AWSCredentials, error) {
+		return creds, nil
+	})
+}
+
+//SigV4Func is SigV4 with credentials from f, called for every HTTP call, so it should cache them until they are close to expiring
+//  calls are signed once the endpoint is chosen, so every retry, failover and hedged request carries a fresh signature
+func SigV4Func(region, service string, f func(ctx context.Context) (AWSCredentials, error)) func(server *Server) error {
+	return func(srv *Server) error {
+		if region == "" || service == "" {
+			return errors.New("jrc: SigV4 needs a region and a service")
+		}
+		srv.sign = func(ctx context.Context, m *Message) error {
+			creds, err := f(ctx)
+			if err != nil {
+				return err
+			}
+			u, err := url.Parse(m.target())
+			if err != nil {
+				return err
+			}
+			if m.Header == nil {
+				m.Header = make(http.Header)
+			}
+			signV4(m.Header, creds, region, service, m.method(), u, m.payload(), time.Now())
+			return nil
+		}
+		return nil
+	}
+}
+
+//signV4 sets the X-Amz-* and Authorization headers on h for a request with method and body to u at t
+//  the host and every X-Amz-* header are signed
+func signV4(h http.Header, creds AWSCredentials, region, service, method string, u *url.URL, body []byte, t time.Time) {
+	stamp := t.UTC().Format("20060102T150405Z")
+	payload := sha256.Sum256(body)
+	h.Set("X-Amz-Date", stamp)
+	h.Set("X-Amz-Content-Sha256", hex.EncodeToString(payload[:]))
+	if creds.SessionToken != "" {
+		h.Set("X-Amz-Security-Token", creds.SessionToken)
+	} else {
+		h.Del("X-Amz-Security-Token")
+	}
+	signed := map[string]string{"host": u.Host}
+	for k, vs := range h {
+		if k = strings.ToLower(k); strings.HasPrefix(k, "x-amz-") {
+			signed[k] = strings.TrimSpace(strings.Join(vs, ","))
+		}
+	}
+	h.Set("Authorization", sigV4Authorization(creds, region, service, method, u, signed, hex.EncodeToString(payload[:]), stamp))
+}
+
+//sigV4Authorization builds the Authorization value for a request with the given lowercase headers to sign and payload hash
+func sigV4Authorization(creds AWSCredentials, region, service, method string, u *url.URL, headers map[string]string, payload, stamp string) string {
+	names := make([]string, 0, len(headers))
+	for k := range headers {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	var canon strings.Builder
+	canon.WriteString(method + "\n")
+	path := u.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	canon.WriteString(awsEscape(path, true) + "\n")
+	canon.WriteString(canonicalQuery(u.Query()) + "\n")
+	for _, k := range names {
+		canon.WriteString(k + ":" + headers[k] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+	canon.WriteString("\n" + signedHeaders + "\n" + payload)
+
+	date := stamp[:8]
+	scope := date + "/" + region + "/" + service + "/aws4_request"
+	sum := sha256.Sum256([]byte(canon.String()))
+	toSign := "AWS4-HMAC-SHA256\n" + stamp + "\n" + scope + "\n" + hex.EncodeToString(sum[:])
+
+	key := []byte("AWS4" + creds.SecretAccessKey)
+	for _, part := range []string{date, region, service, "aws4_request"} {
+		key = hmacSHA256(key, part)
+	}
+	return "AWS4-HMAC-SHA256 Credential=" + creds.AccessKeyID + "/" + scope +
+		", SignedHeaders=" + signedHeaders + ", Signature=" + hex.EncodeToString(hmacSHA256(key, toSign))
+}
+
+func hmacSHA256(key []byte, s string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(s))
+	return mac.Sum(nil)
+}
+
+//canonicalQuery encodes q sorted by key and then value, as SigV4 expects
+func canonicalQuery(q url.Values) string {
+	pairs := make([]string, 0, len(q))
+	for k, vs := range q {
+		for _, v := range vs {
+			pairs = append(pairs, awsEscape(k, false)+"="+awsEscape(v, false))
+		}
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, "&")
+}
+
+//awsEscape percent encodes everything but unreserved characters, and slashes too unless keepSlash
+func awsEscape(s string, keepSlash bool) string {
+	const hexDigits = "0123456789ABCDEF"
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z', '0' <= c && c <= '9', c == '-', c == '_', c == '.', c == '~':
+			b.WriteByte(c)
+		case c == '/' && keepSlash:
+			b.WriteByte(c)
+		default:
+			b.WriteByte('%')
+			b.WriteByte(hexDigits[c>>4])
+			b.WriteByte(hexDigits[c&15])
+		}
+	}
+	return b.String()
+}
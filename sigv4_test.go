@@ -0,0 +1,71 @@
+package jrc
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+var exampleCreds = AWSCredentials{AccessKeyID: "AKIDEXAMPLE", SecretAccessKey: "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY"}
+
+//TestSigV4PostVanilla checks the signature against the post-vanilla case of the AWS SigV4 test suite
+func TestSigV4PostVanilla(t *testing.T) {
+	u, _ := url.Parse("https://example.amazonaws.com/")
+	empty := sha256.Sum256(nil)
+	headers := map[string]string{"host": "example.amazonaws.com", "x-amz-date": "20150830T123600Z"}
+	got := sigV4Authorization(exampleCreds, "us-east-1", "service", http.MethodPost, u, headers, hex.EncodeToString(empty[:]), "20150830T123600Z")
+	want := "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/service/aws4_request, " +
+		"SignedHeaders=host;x-amz-date, Signature=5da7c1a2acd57cee7505fc6676e4e544621c30862966e37dddb68e92efbe5d6b"
+	if got != want {
+		t.Errorf("got  %s\nwant %s", got, want)
+	}
+}
+
+func TestSigV4SignsAmzHeaders(t *testing.T) {
+	u, _ := url.Parse("https://abc.execute-api.us-east-1.amazonaws.com/prod?b=2&a=1")
+	h := make(http.Header)
+	h.Set("Content-Type", "application/json")
+	creds := exampleCreds
+	creds.SessionToken = "token"
+	signV4(h, creds, "us-east-1", "execute-api", http.MethodPost, u, []byte(`{"jsonrpc":"2.0"}`), time.Date(2015, 8, 30, 12, 36, 0, 0, time.UTC))
+
+	if h.Get("X-Amz-Date") != "20150830T123600Z" || h.Get("X-Amz-Security-Token") != "token" {
+		t.Errorf("got headers %v", h)
+	}
+	sum := sha256.Sum256([]byte(`{"jsonrpc":"2.0"}`))
+	if h.Get("X-Amz-Content-Sha256") != hex.EncodeToString(sum[:]) {
+		t.Errorf("got payload hash %s", h.Get("X-Amz-Content-Sha256"))
+	}
+	auth := h.Get("Authorization")
+	if !strings.Contains(auth, "/20150830/us-east-1/execute-api/aws4_request,") ||
+		!strings.Contains(auth, "SignedHeaders=host;x-amz-content-sha256;x-amz-date;x-amz-security-token,") {
+		t.Errorf("got Authorization %s", auth)
+	}
+
+	//signing again without a session token drops the old one
+	signV4(h, exampleCreds, "us-east-1", "execute-api", http.MethodPost, u, nil, time.Now())
+	if h.Get("X-Amz-Security-Token") != "" || strings.Contains(h.Get("Authorization"), "x-amz-security-token") {
+		t.Errorf("stale session token kept: %v", h)
+	}
+}
+
+func TestSigV4SignsGET(t *testing.T) {
+	srv, _ := NewServer("https://abc.execute-api.us-east-1.amazonaws.com/prod", SigV4("us-east-1", "execute-api", exampleCreds))
+	m := &Message{URL: "https://abc.execute-api.us-east-1.amazonaws.com/prod?k=v", Body: []byte(`{"jsonrpc":"2.0"}`), Query: url.Values{"q": {"e30"}}}
+	if err := srv.sign(nil, m); err != nil {
+		t.Fatal(err)
+	}
+	empty := sha256.Sum256(nil)
+	if m.Header.Get("X-Amz-Content-Sha256") != hex.EncodeToString(empty[:]) {
+		t.Errorf("a GET was signed with the body it does not send")
+	}
+	u, _ := url.Parse(m.URL + "&q=e30")
+	headers := map[string]string{"host": u.Host, "x-amz-date": m.Header.Get("X-Amz-Date"), "x-amz-content-sha256": hex.EncodeToString(empty[:])}
+	if want := sigV4Authorization(exampleCreds, "us-east-1", "execute-api", http.MethodGet, u, headers, hex.EncodeToString(empty[:]), m.Header.Get("X-Amz-Date")); m.Header.Get("Authorization") != want {
+		t.Errorf("got  %s\nwant %s", m.Header.Get("Authorization"), want)
+	}
+}
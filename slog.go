@@ -0,0 +1,30 @@
+//go:build go1.21
+
+package jrc
+
+import (
+	"context"
+	"log/slog"
+)
+
+type slogLogger struct {
+	l *slog.Logger
+}
+
+//SlogLogger adapts a *slog.Logger to a Logger, mapping LogLevels onto the matching slog levels
+func SlogLogger(l *slog.Logger) Logger {
+	return slogLogger{l}
+}
+
+func (s slogLogger) Log(level LogLevel, msg string, keyvals ...interface{}) {
+	lvl := slog.LevelDebug
+	switch level {
+	case LogError:
+		lvl = slog.LevelError
+	case LogWarn:
+		lvl = slog.LevelWarn
+	case LogInfo:
+		lvl = slog.LevelInfo
+	}
+	s.l.Log(context.Background(), lvl, msg, keyvals...)
+}
@@ -0,0 +1,68 @@
+package jrc
+
+//SubBatch is one HTTP call's share of a batch, as split up according to MaxBatch or AdaptiveBatch, Profile and ReadOverGET
+type SubBatch struct {
+	Index    int         //position of the HTTP call among those made for the batch
+	Offset   int         //position in the batch of the first request in this sub-batch
+	Requests RPCRequests //the requests sent together in this call
+}
+
+//Split shows how the Server divides rs into HTTP calls, in the order ExecBatchFast returns their response bodies
+//  the i-th request of rs is sent in the sub-batch whose Offset <= i < Offset+len(Requests)
+func (srv *Server) Split(rs RPCRequests) []SubBatch {
+	return srv.split(rs, srv.batchSize())
+}
+
+//split divides rs into sub-batches of at most size requests, any number of them when size is less than 1,
+//  starting a new one wherever the Profile, or whether ReadOverGET applies, changes from one request to the next
+func (srv *Server) split(rs RPCRequests, size int) []SubBatch {
+	if srv.v1 || srv.isUnbatched() {
+		//1.0 has no batches, nor has a Server FallbackToSingle found refusing them, so every request is a call of its own
+		size = 1
+	}
+	if size < 1 {
+		//no limit, so the whole batch goes in one call
+		size = len(rs)
+	}
+	if len(rs) == 0 {
+		return nil
+	}
+	subs := make([]SubBatch, 0, (len(rs)+size-1)/size)
+	for start := 0; start < len(rs); {
+		end := start + size
+		if end > len(rs) {
+			end = len(rs)
+		}
+		if len(srv.profiles) > 0 || len(srv.getMethods) > 0 {
+			p, get := srv.profiles[rs[start].Method], srv.getMethods[rs[start].Method]
+			for i := start + 1; i < end; i++ {
+				if srv.profiles[rs[i].Method] != p || srv.getMethods[rs[i].Method] != get {
+					end = i
+					break
+				}
+			}
+		}
+		subs = append(subs, SubBatch{Index: len(subs), Offset: start, Requests: rs[start:end]})
+		start = end
+	}
+	return subs
+}
+
+//subSizes returns the number of requests in each of subs
+func subSizes(subs []SubBatch) []int {
+	sizes := make([]int, len(subs))
+	for i, sb := range subs {
+		sizes[i] = len(sb.Requests)
+	}
+	return sizes
+}
+
+//SubBatchOf returns the sub-batch of subs holding the i-th request of the batch they were split from
+func SubBatchOf(subs []SubBatch, i int) (SubBatch, bool) {
+	for _, sb := range subs {
+		if i >= sb.Offset && i < sb.Offset+len(sb.Requests) {
+			return sb, true
+		}
+	}
+	return SubBatch{}, false
+}
@@ -0,0 +1,200 @@
+package jrc
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"sync"
+
+	"github.com/cfoxon/jrc/internal/json"
+)
+
+//eventStreams are the Subscriptions of SubscribeEvents and Poll, which live on no connection of a transport
+//  and which Close ends
+type eventStreams struct {
+	mu     sync.Mutex
+	open   map[*Subscription]context.CancelFunc
+	closed bool
+}
+
+//add tracks s until it ends, false if the Server has been closed
+func (es *eventStreams) add(s *Subscription, cancel context.CancelFunc) bool {
+	es.mu.Lock()
+	defer es.mu.Unlock()
+	if es.closed {
+		return false
+	}
+	if es.open == nil {
+		es.open = make(map[*Subscription]context.CancelFunc)
+	}
+	es.open[s] = cancel
+	return true
+}
+
+func (es *eventStreams) remove(s *Subscription) {
+	es.mu.Lock()
+	delete(es.open, s)
+	es.mu.Unlock()
+}
+
+//close ends every stream with ErrClosed
+func (es *eventStreams) close() {
+	es.mu.Lock()
+	open := es.open
+	es.open, es.closed = nil, true
+	es.mu.Unlock()
+	for s, cancel := range open {
+		s.end(ErrClosed)
+		cancel()
+	}
+}
+
+//SubscribeEvents opens the text/event-stream at addr, or at the Server's url if addr is empty, and delivers the
+//  notifications the server sends as its events, a notification or a batch of them in each event's data
+//  they come on a Subscription as with Subscribe; Unsubscribe closes the stream, and the Subscription ends with a
+//  TransportError when the server closes it; ctx only limits opening the stream, and the Subscription's ID is null
+func (srv *Server) SubscribeEvents(ctx context.Context, addr string) (*Subscription, error) {
+	if addr == "" {
+		addr = srv.url.String()
+	}
+	hs, err := srv.callHeaders(ctx, &callConfig{})
+	if err != nil {
+		return nil, err
+	}
+	streamCtx, cancel := context.WithCancel(context.Background())
+	req, err := http.NewRequestWithContext(streamCtx, http.MethodGet, addr, nil)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	for k, vs := range hs {
+		req.Header[k] = vs
+	}
+	req.Header.Set("Accept", "text/event-stream")
+	req.Header.Set("Cache-Control", "no-cache")
+
+	opened := make(chan struct{})
+	go func() {
+		select {
+		case <-ctx.Done():
+			cancel()
+		case <-opened:
+		}
+	}()
+	client := srv.eventClient()
+	resp, err := client.Do(req)
+	close(opened)
+	if err != nil {
+		cancel()
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		b, _ := readLimited(resp.Body, 4096, nil)
+		resp.Body.Close()
+		cancel()
+		return nil, &StatusError{StatusCode: resp.StatusCode, Body: b}
+	}
+	if mt, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type")); mt != "text/event-stream" {
+		resp.Body.Close()
+		cancel()
+		return nil, errors.New("jrc: " + addr + " is not an event stream but " + resp.Header.Get("Content-Type"))
+	}
+
+	var s *Subscription
+	s = newSubscription(ID{}, func() error {
+		srv.events.remove(s)
+		cancel()
+		return nil
+	})
+	if !srv.events.add(s, cancel) {
+		resp.Body.Close()
+		cancel()
+		s.end(ErrClosed)
+		return nil, ErrClosed
+	}
+	go func() {
+		defer client.CloseIdleConnections()
+		defer resp.Body.Close()
+		err := readEvents(bufio.NewReader(resp.Body), srv.maxResp, s.deliver)
+		srv.events.remove(s)
+		s.end(&TransportError{Err: err})
+		cancel()
+	}()
+	return s, nil
+}
+
+//eventClient returns the client for event streams: that of the Server's NetHTTPTransport if it has one, or else
+//  one with the Server's TLS configuration and dialer, without a timeout as streams stay open
+func (srv *Server) eventClient() *http.Client {
+	if t, ok := srv.tr.(*NetHTTPTransport); ok && t.Client != nil {
+		return t.Client
+	}
+	tr := &http.Transport{TLSClientConfig: srv.hc.TLSConfig}
+	if dial := srv.hc.Dial; dial != nil && srv.url.Scheme != "unix" {
+		tr.DialContext = func(_ context.Context, _, addr string) (net.Conn, error) {
+			return dial(addr)
+		}
+	}
+	return &http.Client{Transport: tr}
+}
+
+//readEvents passes the notifications in the event stream r to deliver until it fails, each line being at most limit
+//  bytes if limit is positive
+func readEvents(r *bufio.Reader, limit int, deliver func(Event)) error {
+	var data []byte
+	for {
+		line, err := readLine(r, limit)
+		if err != nil {
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return err
+		}
+		line = bytes.TrimRight(line, "\r\n")
+		if len(line) == 0 {
+			//a blank line ends the event
+			if len(data) > 0 {
+				dispatchEvent(data, deliver)
+			}
+			data = data[:0]
+			continue
+		}
+		field, value := line, []byte(nil)
+		if i := bytes.IndexByte(line, ':'); i >= 0 {
+			field, value = line[:i], bytes.TrimPrefix(line[i+1:], []byte(" "))
+		}
+		//comments, ids, event types and retry times are of no use for notifications
+		if string(field) == "data" {
+			if len(data) > 0 {
+				data = append(data, '\n')
+			}
+			data = append(data, value...)
+		}
+	}
+}
+
+//dispatchEvent passes on the notifications in the data of one event, leaving out anything else
+func dispatchEvent(data []byte, deliver func(Event)) {
+	items, _ := frameItems(data)
+	for _, it := range items {
+		if it.Method == "" || it.Id != nil {
+			continue
+		}
+		ev := Event{Method: it.Method, Params: it.Params}
+		var p struct {
+			Result json.RawMessage `json:"result"`
+		}
+		if len(it.Params) > 0 && it.Params[0] == '{' && json.Unmarshal(it.Params, &p) == nil {
+			ev.Result = p.Result
+		}
+		deliver(ev)
+	}
+}
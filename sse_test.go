@@ -0,0 +1,106 @@
+package jrc_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/cfoxon/jrc"
+)
+
+//eventServer streams events to each client, keeping the stream open until the client goes unless hangUp is set
+func eventServer(t *testing.T, hangUp bool, events ...string) *httptest.Server {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Accept") != "text/event-stream" || r.Header.Get("X-Key") != "k" {
+			http.Error(w, "no", http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "text/event-stream; charset=utf-8")
+		w.(http.Flusher).Flush()
+		for _, ev := range events {
+			fmt.Fprint(w, ev)
+			w.(http.Flusher).Flush()
+		}
+		if !hangUp {
+			<-r.Context().Done()
+		}
+	}))
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+func TestSubscribeEvents(t *testing.T) {
+	ts := eventServer(t, false,
+		": keep-alive\n\n",
+		"id: 1\ndata: {\"jsonrpc\":\"2.0\",\"method\":\"eth_subscription\",\n"+
+			"data: \"params\":{\"subscription\":\"0x1\",\"result\":1}}\n\n",
+		"event: batch\r\ndata: [{\"jsonrpc\":\"2.0\",\"method\":\"tick\",\"params\":[2]},{\"jsonrpc\":\"2.0\",\"id\":9,\"result\":0},"+
+			"{\"jsonrpc\":\"2.0\",\"method\":\"tick\",\"params\":{\"result\":3}}]\r\n\r\n",
+	)
+	srv, _ := jrc.NewServer(ts.URL+"/rpc", jrc.Header("X-Key", "k"))
+	defer srv.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	sub, err := srv.SubscribeEvents(ctx, ts.URL+"/events")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for ev := range sub.C {
+		got = append(got, ev.Method+" "+string(ev.Params)+" "+string(ev.Result))
+		if len(got) == 3 {
+			sub.Unsubscribe()
+		}
+	}
+	want := []string{
+		`eth_subscription {"subscription":"0x1","result":1} 1`,
+		`tick [2] `,
+		`tick {"result":3} 3`,
+	}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	if sub.Err() != nil {
+		t.Errorf("ended with %v after Unsubscribe", sub.Err())
+	}
+}
+
+func TestSubscribeEventsEnds(t *testing.T) {
+	ctx := context.Background()
+	closing := eventServer(t, true, "data: {\"jsonrpc\":\"2.0\",\"method\":\"tick\"}\n\n")
+	srv, _ := jrc.NewServer(closing.URL, jrc.Header("X-Key", "k"))
+	sub, err := srv.SubscribeEvents(ctx, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for range sub.C {
+	}
+	var te *jrc.TransportError
+	if !errors.As(sub.Err(), &te) {
+		t.Errorf("ended with %v when the server closed the stream, want a TransportError", sub.Err())
+	}
+
+	open := eventServer(t, false)
+	sub, _ = srv.SubscribeEvents(ctx, open.URL)
+	srv.Close()
+	for range sub.C {
+	}
+	if !errors.Is(sub.Err(), jrc.ErrClosed) {
+		t.Errorf("ended with %v after Close, want ErrClosed", sub.Err())
+	}
+	if _, err := srv.SubscribeEvents(ctx, open.URL); !errors.Is(err, jrc.ErrClosed) {
+		t.Errorf("opened a stream after Close: %v", err)
+	}
+
+	srv, _ = jrc.NewServer(open.URL)
+	defer srv.Close()
+	var se *jrc.StatusError
+	if _, err := srv.SubscribeEvents(ctx, ""); !errors.As(err, &se) || se.StatusCode != http.StatusBadRequest {
+		t.Errorf("got %v for a refused stream, want a StatusError", err)
+	}
+}
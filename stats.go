@@ -0,0 +1,86 @@
+package jrc
+
+import "time"
+
+//BatchStats describes how a batch was sent, as gathered by WithStats, for tuning MaxCon and MaxBatch
+type BatchStats struct {
+	Wall     time.Duration   //from the first HTTP call of the batch going out to the last coming back
+	Calls    int             //HTTP calls the batch was split into, one per SubBatch
+	Sends    int             //requests made to endpoints for those calls, retries, failover and Hedge included
+	Retries  int             //of calls after a transport failure, see Retry
+	BytesOut int             //of the request bodies, as sent
+	BytesIn  int             //of the response bodies, decoded
+	Latency  []time.Duration //of each call, retries included, in the order of SubBatch.Index; zero for calls never made
+}
+
+//WithStats adds the stats of one batch to s once it returns, so a batch FallbackToSingle sends again in part
+//  or several batches can share one; the entries for each batch's calls are appended to Latency
+func WithStats(s *BatchStats) CallOption {
+	return func(cfg *callConfig) {
+		cfg.stats = s
+	}
+}
+
+//stats tracks a batch for WithStats
+type stats struct {
+	*BatchStats
+	start time.Time
+	base  int //index in Latency of the batch's first call
+}
+
+//newStats returns the tracker for the n calls of the batch of cfg, nil if it has no WithStats
+func newStats(cfg *callConfig, n int) *stats {
+	if cfg.stats == nil {
+		return nil
+	}
+	s := &stats{BatchStats: cfg.stats, start: time.Now(), base: len(cfg.stats.Latency)}
+	s.Latency = append(s.Latency, make([]time.Duration, n)...)
+	return s
+}
+
+//sent records a call going out with a body of n bytes
+func (s *stats) sent(n int) {
+	if s == nil {
+		return
+	}
+	s.Calls++
+	s.BytesOut += n
+}
+
+//done records the response to a call, and what t counted while it was made
+func (s *stats) done(res response, t *tally) {
+	if s == nil {
+		return
+	}
+	if t != nil {
+		s.Sends += t.sends
+		s.Retries += t.retries
+	}
+	s.BytesIn += len(res.b)
+	s.Latency[s.base+res.i] = res.latency
+}
+
+//tally counts the sends and retries of one call, made by the worker alone, hedged sends included as they start there
+type tally struct {
+	sends, retries int
+}
+
+func (t *tally) sent() {
+	if t != nil {
+		t.sends++
+	}
+}
+
+func (t *tally) retried() {
+	if t != nil {
+		t.retries++
+	}
+}
+
+//end records the batch having returned
+func (s *stats) end() {
+	if s == nil {
+		return
+	}
+	s.Wall += time.Since(s.start)
+}
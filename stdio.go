@@ -0,0 +1,78 @@
+package jrc
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"os/exec"
+	"time"
+)
+
+//processStopTimeout is how long a child process is given to exit after its stdin is closed before it is killed
+const processStopTimeout = 5 * time.Second
+
+//NewCommandServer creates a target that speaks JSON RPC to a child process over its stdin and stdout
+//  the process is started on the first call and restarted by the next call if it exits
+//  messages use ContentLengthFraming as LSP and DAP servers expect unless StreamFraming is given
+//  call Close to stop the process
+func NewCommandServer(name string, args []string, options ...func(*Server) error) (*Server, error) {
+	srv, err := newDefaultServer("stdio:" + name)
+	if err != nil {
+		return nil, err
+	}
+	srv.endpoints[0].tr = dialStream(func(context.Context, http.Header) (io.ReadWriteCloser, error) {
+		return startProcess(exec.Command(name, args...))
+	}, ContentLengthFraming)
+	if err = srv.SetOption(options...); err != nil {
+		return nil, err
+	}
+	return srv, nil
+}
+
+//process joins the pipes of a running child process into a single connection
+type process struct {
+	cmd    *exec.Cmd
+	stdin  io.WriteCloser
+	stdout io.ReadCloser
+}
+
+func startProcess(cmd *exec.Cmd) (*process, error) {
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err = cmd.Start(); err != nil {
+		return nil, err
+	}
+	return &process{cmd: cmd, stdin: stdin, stdout: stdout}, nil
+}
+
+func (p *process) Read(b []byte) (int, error) {
+	return p.stdout.Read(b)
+}
+
+func (p *process) Write(b []byte) (int, error) {
+	return p.stdin.Write(b)
+}
+
+//Close closes stdin so the process can exit on its own, killing it if it has not exited within processStopTimeout
+func (p *process) Close() error {
+	p.stdin.Close()
+	done := make(chan error, 1)
+	go func() {
+		done <- p.cmd.Wait()
+	}()
+	t := time.NewTimer(processStopTimeout)
+	defer t.Stop()
+	select {
+	case err := <-done:
+		return err
+	case <-t.C:
+		p.cmd.Process.Kill()
+		return <-done
+	}
+}
@@ -0,0 +1,67 @@
+package jrc_test
+
+import (
+	"context"
+	"errors"
+	"os/exec"
+	"testing"
+	"time"
+
+	"github.com/cfoxon/jrc"
+)
+
+//silentCommand starts a child that reads its input and never answers, exiting when stdin closes
+func silentCommand(t *testing.T) *jrc.Server {
+	t.Helper()
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("no sh to run")
+	}
+	srv, err := jrc.NewCommandServer("sh", []string{"-c", "cat >/dev/null"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return srv
+}
+
+func TestCommandContextCancelsCall(t *testing.T) {
+	srv := silentCommand(t)
+	defer srv.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err := srv.ExecContext(ctx, jrc.RpcRequest{JsonRpc: "2.0", Id: 1, Method: "initialize"})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("got %v, want context.DeadlineExceeded", err)
+	}
+}
+
+func TestCommandCloseFailsCallsInFlight(t *testing.T) {
+	srv := silentCommand(t)
+	errc := make(chan error, 1)
+	go func() {
+		_, err := srv.Exec(jrc.RpcRequest{JsonRpc: "2.0", Id: 1, Method: "initialize"})
+		errc <- err
+	}()
+	time.Sleep(50 * time.Millisecond)
+
+	closed := make(chan struct{})
+	go func() {
+		srv.Close()
+		close(closed)
+	}()
+	select {
+	case <-closed:
+	case <-time.After(3 * time.Second):
+		t.Fatal("Close is waiting on a child that never answers")
+	}
+	select {
+	case err := <-errc:
+		if err == nil {
+			t.Error("call in flight succeeded after Close")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("call in flight still waiting after Close")
+	}
+	if _, err := srv.Exec(jrc.RpcRequest{JsonRpc: "2.0", Id: 2, Method: "shutdown"}); !errors.Is(err, jrc.ErrClosed) {
+		t.Errorf("got %v after Close, want ErrClosed", err)
+	}
+}
@@ -0,0 +1,563 @@
+package jrc
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/cfoxon/jrc/internal/json"
+)
+
+//Framing selects how messages are delimited on tcp:// and ipc:// connections and command pipes
+type Framing int
+
+const (
+	//NewlineFraming ends every message with a newline, as used by most raw socket servers
+	NewlineFraming Framing = iota
+	//ContentLengthFraming prefixes every message with a Content-Length header, as used by LSP style servers
+	ContentLengthFraming
+)
+
+//streamTransport speaks JSON RPC over a single persistent connection, one call at a time
+//  a goroutine reads the connection and matches responses to the call by id, so requests and notifications
+//  the server sends of its own, and late responses to cancelled calls, don't put the stream out of step
+type streamTransport struct {
+	dial     func(ctx context.Context, h http.Header) (io.ReadWriteCloser, error)
+	framing  Framing
+	incoming IncomingHandler
+	notify   func(c *streamConn, method string, params json.RawMessage) bool //takes server notifications in order, ahead of incoming
+	opened   func(c *streamConn)                                             //called with every new connection
+
+	sem    chan struct{} //held by the call in progress
+	mu     sync.Mutex
+	conn   *streamConn
+	closed bool
+}
+
+func newStreamTransport(network, addr string) *streamTransport {
+	return dialStream(func(ctx context.Context, _ http.Header) (io.ReadWriteCloser, error) {
+		var d net.Dialer
+		return d.DialContext(ctx, network, addr)
+	}, NewlineFraming)
+}
+
+//dialStream creates a streamTransport over the connections opened by dial, which is given the headers of the call that needs one
+func dialStream(dial func(ctx context.Context, h http.Header) (io.ReadWriteCloser, error), f Framing) *streamTransport {
+	return &streamTransport{dial: dial, framing: f, sem: make(chan struct{}, 1)}
+}
+
+func (t *streamTransport) RoundTrip(ctx context.Context, m *Message) ([]byte, error) {
+	b, _, err := t.roundTrip(ctx, m)
+	return b, err
+}
+
+//roundTrip makes the call in m, returning the connection it went over
+func (t *streamTransport) roundTrip(ctx context.Context, m *Message) ([]byte, *streamConn, error) {
+	select {
+	case t.sem <- struct{}{}:
+	case <-ctx.Done():
+		return nil, nil, ctx.Err()
+	}
+	defer func() { <-t.sem }()
+	ids := frameIDs(m.Body)
+	c, err := t.connect(ctx, m, ids)
+	if err != nil {
+		return nil, nil, err
+	}
+	var w *waiter
+	if !m.Notify {
+		w = c.expect(ids)
+	}
+	if err := c.send(ctx, m.Body); err != nil {
+		return nil, c, err
+	}
+	if m.Notify {
+		return nil, c, nil
+	}
+	select {
+	case b := <-w.ch:
+		return b, c, nil
+	case <-c.dead:
+		select {
+		case b := <-w.ch:
+			//the response came before the connection closed
+			return b, c, nil
+		default:
+			return nil, c, c.err
+		}
+	case <-ctx.Done():
+		c.abandon(w)
+		return nil, c, ctx.Err()
+	}
+}
+
+//connect returns the open connection, dialing a new one if there is none or the ids of a cancelled call
+//  whose response may still come are about to be reused
+func (t *streamTransport) connect(ctx context.Context, m *Message, ids []ID) (*streamConn, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.closed {
+		return nil, ErrClosed
+	}
+	if t.conn != nil && !t.conn.usable(ids) {
+		t.conn.close(errors.New("jrc: connection dropped"))
+		t.conn = nil
+	}
+	if t.conn == nil {
+		rwc, err := t.dial(ctx, m.Header)
+		if err != nil {
+			return nil, err
+		}
+		t.conn = newStreamConn(rwc, t.framing, t.incoming, t.notify, m.MaxResponseSize)
+		if t.opened != nil {
+			t.opened(t.conn)
+		}
+	}
+	return t.conn, nil
+}
+
+//Close closes the connection, failing the call in progress and any made later with ErrClosed
+func (t *streamTransport) Close() error {
+	t.mu.Lock()
+	c := t.conn
+	t.conn = nil
+	t.closed = true
+	t.mu.Unlock()
+	if c == nil {
+		return nil
+	}
+	return c.close(ErrClosed)
+}
+
+//IncomingHandler answers a request the server sends of its own accord on a tcp://, ipc://, ws:// or command connection,
+//  such as LSP's workspace/configuration, with its result or an error; an error of type *RpcError is sent as it is
+//  and any other as CodeInternalError; it is also called for notifications, such as window/logMessage, with
+//  what it returns dropped
+type IncomingHandler func(ctx context.Context, method string, params json.RawMessage) (interface{}, error)
+
+func (srv *Server) setIncoming(h IncomingHandler) error {
+	found := false
+	for _, ep := range srv.endpoints {
+		if t := streamOf(ep.tr); t != nil {
+			t.mu.Lock()
+			t.incoming = h
+			t.mu.Unlock()
+			found = true
+		}
+	}
+	if !found {
+		return errors.New("jrc: HandleIncoming only applies to tcp://, ipc://, ws:// and command servers")
+	}
+	return nil
+}
+
+//HandleIncoming answers the requests and notifications the server sends with h, which is called on a goroutine of its own
+//  without it requests are answered with CodeMethodNotFound and notifications are dropped; it applies from the next connection
+func HandleIncoming(h IncomingHandler) func(server *Server) error {
+	return func(srv *Server) error {
+		return srv.setIncoming(h)
+	}
+}
+
+//streamOf returns the streamTransport tr runs on, nil for HTTP transports
+func streamOf(tr Transport) *streamTransport {
+	switch t := tr.(type) {
+	case *streamTransport:
+		return t
+	case *wsTransport:
+		return t.streamTransport
+	}
+	return nil
+}
+
+//messageConn is a connection that delimits messages itself, such as a WebSocket, so takes no Framing
+type messageConn interface {
+	readMessage(limit int) ([]byte, error)
+	writeMessage(b []byte) error
+}
+
+//waiter is a call waiting for the response to the requests with ids
+type waiter struct {
+	ids map[ID]bool
+	ch  chan []byte
+}
+
+//streamConn is one connection of a streamTransport, with the goroutine reading it
+type streamConn struct {
+	rwc      io.ReadWriteCloser
+	framing  Framing
+	incoming IncomingHandler
+	notify   func(c *streamConn, method string, params json.RawMessage) bool
+	ctx      context.Context //done with the connection, for the handlers of incoming requests
+	cancel   context.CancelFunc
+	wmu      sync.Mutex //held while a message is written, by a call or an answer to the server
+
+	mu    sync.Mutex
+	wait  *waiter
+	stale map[ID]bool //ids of cancelled calls whose responses may still come
+	dead  chan struct{}
+	err   error //why the connection closed, set before dead is
+}
+
+func newStreamConn(rwc io.ReadWriteCloser, f Framing, h IncomingHandler, notify func(*streamConn, string, json.RawMessage) bool, limit int) *streamConn {
+	ctx, cancel := context.WithCancel(context.Background())
+	c := &streamConn{rwc: rwc, framing: f, incoming: h, notify: notify, ctx: ctx, cancel: cancel, dead: make(chan struct{})}
+	go c.read(limit)
+	return c
+}
+
+//read routes every message from the connection until it fails
+func (c *streamConn) read(limit int) {
+	mc, framed := c.rwc.(messageConn)
+	var r *bufio.Reader
+	if !framed {
+		r = bufio.NewReader(c.rwc)
+	}
+	for {
+		var b []byte
+		var err error
+		if framed {
+			b, err = mc.readMessage(limit)
+		} else {
+			b, err = readFrame(r, c.framing, limit)
+		}
+		if err != nil {
+			c.close(err)
+			return
+		}
+		c.route(b)
+	}
+}
+
+//usable reports whether the connection is open and none of ids belongs to a cancelled call
+func (c *streamConn) usable(ids []ID) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.err != nil {
+		return false
+	}
+	for _, id := range ids {
+		if c.stale[id] {
+			return false
+		}
+	}
+	return true
+}
+
+//isDead reports whether the connection has closed, after which err is set
+func (c *streamConn) isDead() bool {
+	select {
+	case <-c.dead:
+		return true
+	default:
+		return false
+	}
+}
+
+//expect registers the call waiting for the response to ids
+func (c *streamConn) expect(ids []ID) *waiter {
+	w := &waiter{ids: make(map[ID]bool, len(ids)), ch: make(chan []byte, 1)}
+	for _, id := range ids {
+		w.ids[id] = true
+	}
+	c.mu.Lock()
+	c.wait = w
+	c.mu.Unlock()
+	return w
+}
+
+//abandon stops waiting for w, whose response is dropped if it comes later
+func (c *streamConn) abandon(w *waiter) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.wait != w {
+		return
+	}
+	c.wait = nil
+	if c.stale == nil {
+		c.stale = make(map[ID]bool)
+	}
+	for id := range w.ids {
+		c.stale[id] = true
+	}
+}
+
+//send writes a message, closing the connection if ctx is done before the write completes
+func (c *streamConn) send(ctx context.Context, b []byte) error {
+	var stop chan struct{}
+	if ctx.Done() != nil {
+		stop = make(chan struct{})
+		defer close(stop)
+		go func() {
+			select {
+			case <-ctx.Done():
+				select {
+				case <-stop:
+					//the write finished first
+				default:
+					c.close(ctx.Err())
+				}
+			case <-stop:
+			}
+		}()
+	}
+	if err := c.write(b); err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		c.close(err)
+		return err
+	}
+	return nil
+}
+
+func (c *streamConn) write(b []byte) error {
+	c.wmu.Lock()
+	defer c.wmu.Unlock()
+	if mc, ok := c.rwc.(messageConn); ok {
+		return mc.writeMessage(b)
+	}
+	return writeFrame(c.rwc, c.framing, b)
+}
+
+//close closes the connection once, failing the waiting call with err and returning the error from closing it
+func (c *streamConn) close(err error) error {
+	c.mu.Lock()
+	if c.err != nil {
+		c.mu.Unlock()
+		return nil
+	}
+	c.err = err
+	close(c.dead)
+	c.mu.Unlock()
+	c.cancel()
+	return c.rwc.Close()
+}
+
+//frameItem is what routing needs of a message in either direction, the id being absent for a notification
+type frameItem struct {
+	Id     json.RawMessage `json:"id"`
+	Method string          `json:"method"`
+	Params json.RawMessage `json:"params"`
+}
+
+//frameItems decodes the message or batch of messages in b, nil if it is neither
+func frameItems(b []byte) ([]frameItem, bool) {
+	b = bytes.TrimSpace(b)
+	if len(b) > 0 && b[0] == '[' {
+		var items []frameItem
+		if json.Unmarshal(b, &items) != nil {
+			return nil, true
+		}
+		return items, true
+	}
+	var item frameItem
+	if json.Unmarshal(b, &item) != nil {
+		return nil, false
+	}
+	return []frameItem{item}, false
+}
+
+//frameIDs returns the ids of the requests in b, leaving out notifications
+func frameIDs(b []byte) []ID {
+	items, _ := frameItems(b)
+	ids := make([]ID, 0, len(items))
+	for _, it := range items {
+		var id ID
+		if it.Id != nil && json.Unmarshal(it.Id, &id) == nil {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+//route hands a response to the call it answers and requests or notifications from the server to the IncomingHandler
+//  a response that answers nothing is dropped, except that one with only null ids, as sent for a message the
+//  server could not parse, or that can't be decoded goes to the waiting call
+func (c *streamConn) route(b []byte) {
+	items, batch := frameItems(b)
+	if len(items) > 0 && items[0].Method != "" {
+		if !batch && items[0].Id == nil && c.notify != nil && c.notify(c, items[0].Method, items[0].Params) {
+			return
+		}
+		go c.answer(items, batch)
+		return
+	}
+	c.mu.Lock()
+	w, matched := c.wait, true
+	for _, it := range items {
+		var id ID
+		json.Unmarshal(it.Id, &id)
+		if !id.IsNull() {
+			matched = w != nil && w.ids[id]
+			delete(c.stale, id)
+			if matched {
+				break
+			}
+		}
+	}
+	if matched {
+		c.wait = nil
+	}
+	c.mu.Unlock()
+	if matched && w != nil {
+		w.ch <- b
+	}
+}
+
+//answer runs the IncomingHandler for each of items and writes the responses to the requests among them
+func (c *streamConn) answer(items []frameItem, batch bool) {
+	var resps []*RpcResponse
+	for _, it := range items {
+		if it.Method == "" || it.Id == nil && c.incoming == nil {
+			continue
+		}
+		result, err := c.handle(it)
+		if it.Id == nil {
+			continue
+		}
+		resp := &RpcResponse{JSONRPC: "2.0"}
+		json.Unmarshal(it.Id, &resp.ID)
+		if err == nil {
+			resp.Result, err = json.Marshal(result)
+		}
+		if err != nil {
+			var rpcErr *RpcError
+			if !errors.As(err, &rpcErr) {
+				rpcErr = &RpcError{Code: CodeInternalError, Message: err.Error()}
+			}
+			resp.Result, resp.Error = nil, rpcErr
+		}
+		resps = append(resps, resp)
+	}
+	if len(resps) == 0 {
+		return
+	}
+	var b []byte
+	var err error
+	if batch {
+		b, err = json.Marshal(resps)
+	} else {
+		b, err = json.Marshal(resps[0])
+	}
+	if err == nil {
+		err = c.write(b)
+	}
+	if err != nil {
+		c.close(err)
+	}
+}
+
+//handle calls the IncomingHandler for one request, answering a panic or a missing handler with an error
+func (c *streamConn) handle(it frameItem) (result interface{}, err error) {
+	if c.incoming == nil {
+		return nil, &RpcError{Code: CodeMethodNotFound, Message: "Method not found"}
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			result, err = nil, &RpcError{Code: CodeInternalError, Message: fmt.Sprint("panic: ", p)}
+		}
+	}()
+	return c.incoming(c.ctx, it.Method, it.Params)
+}
+
+func writeFrame(w io.Writer, f Framing, body []byte) error {
+	var msg []byte
+	switch f {
+	case ContentLengthFraming:
+		msg = append([]byte("Content-Length: "+strconv.Itoa(len(body))+"\r\n\r\n"), body...)
+	default:
+		msg = append(append(make([]byte, 0, len(body)+1), body...), '\n')
+	}
+	_, err := w.Write(msg)
+	return err
+}
+
+func readFrame(r *bufio.Reader, f Framing, limit int) ([]byte, error) {
+	if f != ContentLengthFraming {
+		for {
+			line, err := readLine(r, limit)
+			if err != nil {
+				return nil, err
+			}
+			//blank keep-alive lines carry no message
+			if line = bytes.TrimSpace(line); len(line) > 0 {
+				return line, nil
+			}
+		}
+	}
+	n := -1
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		line = strings.TrimSpace(line)
+		if line == "" {
+			break
+		}
+		if k, v, ok := strings.Cut(line, ":"); ok && strings.EqualFold(strings.TrimSpace(k), "Content-Length") {
+			if n, err = strconv.Atoi(strings.TrimSpace(v)); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if n < 0 {
+		return nil, errors.New("jrc: message without Content-Length")
+	}
+	if limit > 0 && n > limit {
+		return nil, ErrResponseTooLarge
+	}
+	b := make([]byte, n)
+	if _, err := io.ReadFull(r, b); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+//readLine reads up to and including the next newline, failing with ErrResponseTooLarge once past limit bytes if limit is positive
+func readLine(r *bufio.Reader, limit int) ([]byte, error) {
+	var line []byte
+	for {
+		frag, err := r.ReadSlice('\n')
+		if limit > 0 && len(line)+len(frag) > limit+1 {
+			return nil, ErrResponseTooLarge
+		}
+		line = append(line, frag...)
+		if err != bufio.ErrBufferFull {
+			return line, err
+		}
+	}
+}
+
+func (srv *Server) setFraming(f Framing) error {
+	found := false
+	for _, ep := range srv.endpoints {
+		if t, ok := ep.tr.(*streamTransport); ok {
+			t.mu.Lock()
+			t.framing = f
+			t.mu.Unlock()
+			found = true
+		}
+	}
+	if !found {
+		return errors.New("jrc: framing only applies to tcp://, ipc:// and command servers")
+	}
+	return nil
+}
+
+//StreamFraming sets how messages are delimited on tcp:// and ipc:// connections, NewlineFraming by default
+//  it applies from the next connection
+func StreamFraming(f Framing) func(server *Server) error {
+	return func(srv *Server) error {
+		return srv.setFraming(f)
+	}
+}
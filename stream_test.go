@@ -0,0 +1,159 @@
+package jrc_test
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cfoxon/jrc"
+	"github.com/goccy/go-json"
+)
+
+//lineServer accepts a single connection and passes each newline delimited message to serve, which writes the replies itself
+func lineServer(t *testing.T, serve func(msg []byte, write func(string))) string {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { l.Close() })
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		var mu sync.Mutex
+		write := func(s string) {
+			mu.Lock()
+			defer mu.Unlock()
+			conn.Write([]byte(s + "\n"))
+		}
+		r := bufio.NewReader(conn)
+		for {
+			line, err := r.ReadBytes('\n')
+			if err != nil {
+				return
+			}
+			serve(line, write)
+		}
+	}()
+	return "tcp://" + l.Addr().String()
+}
+
+type streamCall struct {
+	Id     json.RawMessage `json:"id"`
+	Method string          `json:"method"`
+}
+
+//call decodes the message from the client, the first of a batch
+func call(msg []byte) streamCall {
+	var qs []streamCall
+	if json.Unmarshal(msg, &qs) == nil && len(qs) > 0 {
+		return qs[0]
+	}
+	var q streamCall
+	json.Unmarshal(msg, &q)
+	return q
+}
+
+func TestStreamMatchesResponsesByID(t *testing.T) {
+	answers := make(chan string, 1)
+	addr := lineServer(t, func(msg []byte, write func(string)) {
+		q := call(msg)
+		if q.Method == "" {
+			//the client's answer to the server's own request
+			answers <- string(msg)
+			return
+		}
+		//a late response to some other call, a notification and a request come before the response
+		write(`{"jsonrpc":"2.0","id":99,"result":"stale"}`)
+		write(`{"jsonrpc":"2.0","method":"window/logMessage","params":{"message":"hi"}}`)
+		write(`{"jsonrpc":"2.0","id":"s1","method":"workspace/configuration","params":[]}`)
+		write(`{"jsonrpc":"2.0","id":` + string(q.Id) + `,"result":"ok"}`)
+	})
+	notes := make(chan string, 2)
+	srv, err := jrc.NewServer(addr, jrc.HandleIncoming(func(ctx context.Context, method string, params json.RawMessage) (interface{}, error) {
+		notes <- method
+		return map[string]int{"tabSize": 4}, nil
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer srv.Close()
+
+	resp, err := srv.Exec(jrc.RpcRequest{JsonRpc: "2.0", Id: 7, Method: "initialize"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(resp.Result) != `"ok"` {
+		t.Errorf("got result %s, want \"ok\"", resp.Result)
+	}
+	got := map[string]bool{<-notes: true, <-notes: true}
+	if !got["window/logMessage"] || !got["workspace/configuration"] {
+		t.Errorf("handler saw %v", got)
+	}
+	select {
+	case a := <-answers:
+		if !strings.Contains(a, `"id":"s1"`) || !strings.Contains(a, `"tabSize":4`) {
+			t.Errorf("server request answered with %s", a)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("server request was not answered")
+	}
+}
+
+func TestStreamRefusesServerRequestsByDefault(t *testing.T) {
+	answered := make(chan string, 1)
+	addr := lineServer(t, func(msg []byte, write func(string)) {
+		if !strings.Contains(string(msg), `"method"`) {
+			answered <- string(msg)
+			return
+		}
+		write(`{"jsonrpc":"2.0","id":1,"method":"client/registerCapability"}`)
+	})
+	srv, _ := jrc.NewServer(addr)
+	defer srv.Close()
+	if err := srv.Notify(jrc.RpcNotification{JsonRpc: "2.0", Method: "initialized"}); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case a := <-answered:
+		if !strings.Contains(a, `-32601`) {
+			t.Errorf("got %s, want a Method not found error", a)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("server request was not answered")
+	}
+}
+
+func TestStreamContextCancelsCall(t *testing.T) {
+	//lineServer takes a single connection, so the second call fails if the first one dropped it
+	addr := lineServer(t, func(msg []byte, write func(string)) {
+		if q := call(msg); string(q.Id) == "2" {
+			write(`{"jsonrpc":"2.0","id":2,"result":"second"}`)
+		}
+	})
+	srv, _ := jrc.NewServer(addr)
+	defer srv.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err := srv.ExecContext(ctx, jrc.RpcRequest{JsonRpc: "2.0", Id: 1, Method: "hang"})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("got %v, want context.DeadlineExceeded", err)
+	}
+	if time.Since(start) > time.Second {
+		t.Errorf("cancelled call took %v", time.Since(start))
+	}
+	resp, err := srv.Exec(jrc.RpcRequest{JsonRpc: "2.0", Id: 2, Method: "next"})
+	if err != nil || string(resp.Result) != `"second"` {
+		t.Fatalf("got %v, %v", resp, err)
+	}
+}
@@ -0,0 +1,83 @@
+package jrc
+
+import (
+	"fmt"
+	"strings"
+)
+
+//ValidationError is a response body rejected by Strict for breaking the JSON RPC 2.0 spec
+type ValidationError struct {
+	Body     []byte
+	Problems []string //one for each thing wrong, naming the position and id of the response
+}
+
+//Error implements the error interface
+func (e *ValidationError) Error() string {
+	return "jrc: invalid response: " + strings.Join(e.Problems, "; ")
+}
+
+//Is matches ErrParse
+func (e *ValidationError) Is(target error) bool {
+	return target == ErrParse
+}
+
+func (srv *Server) setStrict(b bool) error {
+	srv.strict = b
+	return nil
+}
+
+//Strict rejects responses without "jsonrpc": "2.0", with both or neither of result and error, or with an id
+//  matching none of the requests, returning a *ValidationError; only errors may have a null id
+//  responses are checked by ExecBatch, Exec and ExecBatchStream, the bodies returned by ExecBatchFast are not
+func Strict(b bool) func(server *Server) error {
+	return func(srv *Server) error {
+		return srv.setStrict(b)
+	}
+}
+
+//validator checks responses against the requests they answer for Strict
+type validator struct {
+	ids map[ID]bool
+	v1  bool //1.0 responses have no jsonrpc member
+}
+
+//validator returns the Server's validator for the responses to rs, nil unless it is Strict
+func (srv *Server) validator(rs RPCRequests) *validator {
+	if !srv.strict {
+		return nil
+	}
+	v := &validator{ids: make(map[ID]bool, len(rs)), v1: srv.v1}
+	for _, r := range rs {
+		v.ids[r.ID()] = true
+	}
+	return v
+}
+
+//check validates the responses decoded from b, before a null result alongside an error is dropped
+func (v *validator) check(b []byte, resps []RpcResponse) error {
+	var problems []string
+	for i, r := range resps {
+		at := fmt.Sprintf("response %d (id %s)", i, r.ID)
+		if !v.v1 && r.JSONRPC != "2.0" {
+			problems = append(problems, fmt.Sprintf("%s: jsonrpc is %q, not \"2.0\"", at, r.JSONRPC))
+		}
+		result := r.Result != nil
+		if v.v1 && r.Error != nil && string(r.Result) == "null" {
+			//1.0 errors come with "result": null
+			result = false
+		}
+		switch {
+		case result && r.Error != nil:
+			problems = append(problems, at+": has both result and error")
+		case !result && r.Error == nil:
+			problems = append(problems, at+": has neither result nor error")
+		}
+		if !v.ids[r.ID] && !(r.ID.IsNull() && r.Error != nil) {
+			problems = append(problems, at+": id matches no request")
+		}
+	}
+	if len(problems) > 0 {
+		return &ValidationError{Body: append([]byte(nil), b...), Problems: problems}
+	}
+	return nil
+}
@@ -0,0 +1,162 @@
+package jrc
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+
+	"github.com/cfoxon/jrc/internal/json"
+)
+
+//maxSubscriptionQueue is how many events a Subscription holds for a consumer that is behind before it is ended
+const maxSubscriptionQueue = 20000
+
+//ErrSubscriptionOverflow ends a Subscription whose consumer fell too far behind
+var ErrSubscriptionOverflow = errors.New("jrc: subscription queue overflow")
+
+//Event is one notification received for a Subscription
+type Event struct {
+	Method string          //the notification's method, such as eth_subscription
+	Params json.RawMessage //the notification's params in full
+	Result json.RawMessage //the "result" member of the params, if they are an object with one
+	Gap    bool            //the connection dropped and the subscription was made again, so notifications may have been missed; nothing else is set
+}
+
+//Subscription delivers the notifications the server sends for a subscription made with Subscribe, in order
+type Subscription struct {
+	C <-chan Event //closed once the subscription has ended, see Err
+
+	c     chan Event
+	unsub func() error //asks the server to end the subscription
+
+	mu    sync.Mutex
+	id    ID
+	queue []Event
+	err   error
+	ended bool
+	wake  chan struct{}
+	done  chan struct{} //closed when the subscription ends
+}
+
+//subscriber is a transport able to keep subscriptions, such as one of a ws:// endpoint
+type subscriber interface {
+	subscribe(ctx context.Context, r *RpcRequest, unsubscribe string) (*Subscription, error)
+}
+
+//Subscribe calls method, e.g. eth_subscribe, with params built by Params, and returns the notifications for the subscription it starts
+//  it needs a ws:// or wss:// Server; the subscription is ended with the method named by replacing the
+//  "subscribe" at the end of method with "unsubscribe", e.g. eth_unsubscribe; see Resubscribe for surviving reconnects
+func (srv *Server) Subscribe(ctx context.Context, method string, params ...interface{}) (*Subscription, error) {
+	t, ok := srv.endpoints[0].tr.(subscriber)
+	if !ok || srv.tr != nil {
+		return nil, errors.New("jrc: Subscribe needs a ws:// or wss:// Server")
+	}
+	r, err := NewRequest(method, params...)
+	if err != nil {
+		return nil, err
+	}
+	r.SetID(srv.ids.NextID())
+	return t.subscribe(ctx, r, strings.TrimSuffix(method, "subscribe")+"unsubscribe")
+}
+
+func newSubscription(id ID, unsub func() error) *Subscription {
+	c := make(chan Event)
+	s := &Subscription{id: id, C: c, c: c, unsub: unsub, wake: make(chan struct{}, 1), done: make(chan struct{})}
+	go s.forward()
+	return s
+}
+
+//forward passes queued events on to C, so receiving notifications never waits for the consumer
+func (s *Subscription) forward() {
+	defer close(s.c)
+	for {
+		s.mu.Lock()
+		if len(s.queue) == 0 {
+			s.mu.Unlock()
+			select {
+			case <-s.wake:
+			case <-s.done:
+				return
+			}
+			continue
+		}
+		ev := s.queue[0]
+		s.queue[0] = Event{}
+		s.queue = s.queue[1:]
+		s.mu.Unlock()
+		select {
+		case s.c <- ev:
+		case <-s.done:
+			return
+		}
+	}
+}
+
+//deliver queues ev for the consumer, ending the subscription if too many are waiting
+func (s *Subscription) deliver(ev Event) {
+	s.mu.Lock()
+	if s.ended {
+		s.mu.Unlock()
+		return
+	}
+	if len(s.queue) >= maxSubscriptionQueue {
+		s.mu.Unlock()
+		s.end(ErrSubscriptionOverflow)
+		return
+	}
+	s.queue = append(s.queue, ev)
+	s.mu.Unlock()
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+//end stops the subscription with err, dropping the events not yet received, false if it had already ended
+func (s *Subscription) end(err error) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.ended {
+		return false
+	}
+	s.ended, s.err = true, err
+	s.queue = nil
+	close(s.done)
+	return true
+}
+
+//Unsubscribe ends the subscription, telling the server so if the connection is still up, and closes C
+func (s *Subscription) Unsubscribe() error {
+	if !s.end(nil) {
+		return nil
+	}
+	return s.unsub()
+}
+
+//ID returns the id the server gave the subscription, which changes when it is made again after a reconnect
+func (s *Subscription) ID() ID {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.id
+}
+
+func (s *Subscription) setID(id ID) {
+	s.mu.Lock()
+	s.id = id
+	s.mu.Unlock()
+}
+
+//isEnded reports whether the subscription has ended
+func (s *Subscription) isEnded() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.ended
+}
+
+//Err returns why C was closed: nil after Unsubscribe, ErrSubscriptionOverflow, or the error that ended the connection
+func (s *Subscription) Err() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.err
+}
@@ -0,0 +1,85 @@
+package jrc
+
+import (
+	"errors"
+	"net"
+	"time"
+
+	"github.com/valyala/fasthttp"
+)
+
+func (srv *Server) setDialTimeout(d time.Duration) error {
+	if d < 0 {
+		return errors.New("jrc: DialTimeout cannot be negative")
+	}
+	if srv.hc.Dial != nil && srv.dialLimit == 0 {
+		return errors.New("jrc: DialTimeout cannot be combined with Dial, Proxy or a unix address")
+	}
+	srv.dialLimit = d
+	if d == 0 {
+		srv.hc.Dial = nil
+		return nil
+	}
+	srv.hc.Dial = func(addr string) (net.Conn, error) {
+		if srv.hc.DialDualStack {
+			return fasthttp.DialDualStackTimeout(addr, srv.dialLimit)
+		}
+		return fasthttp.DialTimeout(addr, srv.dialLimit)
+	}
+	return nil
+}
+
+//DialTimeout limits how long opening a connection to an endpoint may take, fasthttp's default is 3s
+//  it replaces the dialer, so it cannot be combined with Dial, Proxy or unix addresses
+func DialTimeout(d time.Duration) func(server *Server) error {
+	return func(srv *Server) error {
+		return srv.setDialTimeout(d)
+	}
+}
+
+func (srv *Server) setReadTimeout(d time.Duration) error {
+	if d < 0 {
+		return errors.New("jrc: ReadTimeout cannot be negative")
+	}
+	srv.hc.ReadTimeout = d
+	return nil
+}
+
+//ReadTimeout limits how long reading a whole response may take, zero for no limit as by default
+//  a context deadline on the call applies as well
+func ReadTimeout(d time.Duration) func(server *Server) error {
+	return func(srv *Server) error {
+		return srv.setReadTimeout(d)
+	}
+}
+
+func (srv *Server) setWriteTimeout(d time.Duration) error {
+	if d < 0 {
+		return errors.New("jrc: WriteTimeout cannot be negative")
+	}
+	srv.hc.WriteTimeout = d
+	return nil
+}
+
+//WriteTimeout limits how long sending a whole request may take, zero for no limit as by default
+func WriteTimeout(d time.Duration) func(server *Server) error {
+	return func(srv *Server) error {
+		return srv.setWriteTimeout(d)
+	}
+}
+
+func (srv *Server) setMaxIdleConnDuration(d time.Duration) error {
+	if d < 0 {
+		return errors.New("jrc: MaxIdleConnDuration cannot be negative")
+	}
+	srv.hc.MaxIdleConnDuration = d
+	return nil
+}
+
+//MaxIdleConnDuration closes connections left idle for longer than d, fasthttp's default is 10s
+//  servers or load balancers that drop idle connections sooner cause failed calls unless it is set below their limit
+func MaxIdleConnDuration(d time.Duration) func(server *Server) error {
+	return func(srv *Server) error {
+		return srv.setMaxIdleConnDuration(d)
+	}
+}
@@ -0,0 +1,46 @@
+package jrc
+
+import "crypto/tls"
+
+func (srv *Server) setTLSConfig(cfg *tls.Config) error {
+	srv.hc.TLSConfig = cfg
+	return nil
+}
+
+func (srv *Server) addClientCertificate(cert tls.Certificate) error {
+	var cfg *tls.Config
+	if srv.hc.TLSConfig != nil {
+		cfg = srv.hc.TLSConfig.Clone()
+	} else {
+		cfg = &tls.Config{}
+	}
+	cfg.Certificates = append(cfg.Certificates, cert)
+	srv.hc.TLSConfig = cfg
+	return nil
+}
+
+//TLSConfig sets the TLS configuration used for https connections, e.g. to trust custom root CAs or set ServerName
+//  it replaces any client certificates added before it
+func TLSConfig(cfg *tls.Config) func(server *Server) error {
+	return func(srv *Server) error {
+		return srv.setTLSConfig(cfg)
+	}
+}
+
+//ClientCertificate presents cert to servers that require mutual TLS
+func ClientCertificate(cert tls.Certificate) func(server *Server) error {
+	return func(srv *Server) error {
+		return srv.addClientCertificate(cert)
+	}
+}
+
+//ClientCertificateFile loads a PEM encoded certificate and key pair to present to servers that require mutual TLS
+func ClientCertificateFile(certFile, keyFile string) func(server *Server) error {
+	return func(srv *Server) error {
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return err
+		}
+		return srv.addClientCertificate(cert)
+	}
+}
@@ -0,0 +1,20 @@
+package jrc
+
+import "context"
+
+//Tracer starts spans around the work a Server does for each call
+//  its methods are called from the Server's workers and must be safe for concurrent use
+type Tracer interface {
+	//StartBatch begins a span covering an Exec, ExecBatch or Notify call that makes the given number of HTTP calls
+	StartBatch(ctx context.Context, calls int) (context.Context, func(err error))
+	//StartCall begins a child span for one HTTP request, and may add headers such as traceparent to m
+	StartCall(ctx context.Context, m *Message) (context.Context, func(err error))
+}
+
+//Trace reports the Server's calls to t, nil stops tracing
+func Trace(t Tracer) func(server *Server) error {
+	return func(srv *Server) error {
+		srv.tracer = t
+		return nil
+	}
+}
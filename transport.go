@@ -0,0 +1,62 @@
+package jrc
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+//Transport carries JSON RPC messages to a server and returns the response bodies
+//  the fasthttp HostClient is used by default, see UseTransport to replace it
+type Transport interface {
+	//RoundTrip sends m and returns the raw response body, errors are for transport failures only
+	RoundTrip(ctx context.Context, m *Message) ([]byte, error)
+}
+
+//Message is a single marshalled request or batch on its way to the server
+type Message struct {
+	URL    string      //the address of the endpoint being tried
+	Header http.Header //headers from Header, auth and CallOptions, transports without headers ignore them
+	Body   []byte      //the JSON RPC request or batch
+	Notify bool        //the body only holds notifications so no response is expected
+
+	MaxResponseSize int  //limit on the size of the decoded response body, zero for none; ErrResponseTooLarge beyond it
+	Pooled          bool //the response body may be drawn from the buffer pool, see PoolBuffers
+	Requests        int  //the number of requests in Body, zero if unknown
+
+	Jar       http.CookieJar //cookies to send and to store those of the response in, see Cookies; transports without cookies ignore it
+	Challenge bool           //a 401 response is returned as a *StatusError with its headers, for DigestAuth to answer
+	Response  http.Header    //when not nil, HTTP transports add the response headers to it, as Dump does
+	Query     url.Values     //when not nil, HTTP transports send a GET with these added to the URL's query and no body, see ReadOverGET
+	Redirects int            //how many redirects the fasthttp transport follows, see FollowRedirects
+
+	profile *profile //of the requests in Body, nil for the Server's settings
+	path    *url.URL //from WithPath, nil for the endpoint's
+	tally   *tally   //for WithStats, nil without it
+}
+
+func (srv *Server) setTransport(t Transport) error {
+	srv.tr = t
+	return nil
+}
+
+//UseTransport replaces how messages are sent to every endpoint of the Server, e.g. with a NetHTTPTransport
+func UseTransport(t Transport) func(server *Server) error {
+	return func(srv *Server) error {
+		return srv.setTransport(t)
+	}
+}
+
+//StatusError is returned by HTTP transports for responses with a 5xx status, or 413 for a batch too large, causing failover to the next endpoint
+//  a redirect still unresolved after the hops allowed by FollowRedirects is returned as one too
+//  if no endpoint succeeds the last Body is still parsed as the response, as JSON RPC servers may send errors this way
+type StatusError struct {
+	StatusCode int
+	Body       []byte
+	Header     http.Header //the response headers, only kept for 401 challenges
+}
+
+func (e *StatusError) Error() string {
+	return "jrc: server responded with HTTP status " + strconv.Itoa(e.StatusCode)
+}
@@ -0,0 +1,73 @@
+package jrc
+
+import (
+	"context"
+
+	"github.com/cfoxon/jrc/internal/json"
+)
+
+//ExecInto executes a single remote procedure call and unmarshals the Result into result, which must be a pointer
+//  an RpcError returned by the Server is returned as the error
+func (srv *Server) ExecInto(r RpcRequest, result interface{}, opts ...CallOption) error {
+	return srv.ExecIntoContext(context.Background(), r, result, opts...)
+}
+
+//ExecIntoContext is ExecInto with a context to cancel the call or apply a deadline
+func (srv *Server) ExecIntoContext(ctx context.Context, r RpcRequest, result interface{}, opts ...CallOption) error {
+	resp, err := srv.ExecContext(ctx, r, opts...)
+	if err != nil {
+		return err
+	}
+	if resp.Error != nil {
+		return resp.Error
+	}
+	if len(resp.Result) > 0 {
+		return srv.codec.Unmarshal(resp.Result, result)
+	}
+	return nil
+}
+
+//ExecTyped executes a single remote procedure call and decodes the Result into a T
+//  an RpcError returned by the Server is returned as the error
+func ExecTyped[T any](srv *Server, r RpcRequest, opts ...CallOption) (T, error) {
+	return ExecTypedContext[T](context.Background(), srv, r, opts...)
+}
+
+//ExecTypedContext is ExecTyped with a context to cancel the call or apply a deadline
+func ExecTypedContext[T any](ctx context.Context, srv *Server, r RpcRequest, opts ...CallOption) (T, error) {
+	var v T
+	err := srv.ExecIntoContext(ctx, r, &v, opts...)
+	return v, err
+}
+
+//Call executes method with params built by Params, giving the request the next id from the Server's IDGenerator
+//  the Result is returned undecoded, and an RpcError returned by the Server is returned as the error
+func (srv *Server) Call(ctx context.Context, method string, params ...interface{}) (json.RawMessage, error) {
+	r, err := NewRequest(method, params...)
+	if err != nil {
+		return nil, err
+	}
+	r.SetID(srv.ids.NextID())
+	resp, err := srv.ExecContext(ctx, *r)
+	if err != nil {
+		return nil, err
+	}
+	if resp.Error != nil {
+		return nil, resp.Error
+	}
+	return resp.Result, nil
+}
+
+//CallTyped is Call with the Result decoded into a T, with the Server's Codec if srv is a *Server and as JSON otherwise
+func CallTyped[T any](ctx context.Context, srv Caller, method string, params ...interface{}) (T, error) {
+	var v T
+	res, err := srv.Call(ctx, method, params...)
+	if err == nil && len(res) > 0 {
+		var c Codec = JSONCodec{}
+		if s, ok := srv.(*Server); ok {
+			c = s.codec
+		}
+		err = c.Unmarshal(res, &v)
+	}
+	return v, err
+}
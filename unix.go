@@ -0,0 +1,51 @@
+package jrc
+
+import (
+	"net"
+	"net/url"
+	"strings"
+
+	"github.com/valyala/fasthttp"
+)
+
+//unixDialer returns a fasthttp.DialFunc that connects to the unix socket at path for every address
+func unixDialer(path string) fasthttp.DialFunc {
+	return func(string) (net.Conn, error) {
+		return net.Dial("unix", path)
+	}
+}
+
+//withPath returns u with the path of p, and its query if it has one, for WithPath
+func withPath(u, p *url.URL) *url.URL {
+	v := *u
+	v.Path, v.RawPath = p.Path, p.RawPath
+	if !strings.HasPrefix(v.Path, "/") {
+		v.Path, v.RawPath = "/"+v.Path, ""
+	}
+	if p.RawQuery != "" || p.ForceQuery {
+		v.RawQuery, v.ForceQuery = p.RawQuery, p.ForceQuery
+	}
+	return &v
+}
+
+//requestURL is the url HTTP requests for u are made to, its path and query as they are and without the fragment,
+//  or with those of path when it is not nil
+//  for unix sockets the url path names the socket, so requests go to the root of a placeholder host
+func requestURL(u, path *url.URL) string {
+	if u.Scheme == "unix" {
+		u = &url.URL{Scheme: "http", Host: "localhost", Path: "/"}
+	}
+	if path != nil {
+		u = withPath(u, path)
+	}
+	if u.Fragment != "" || u.Path == "" && u.Opaque == "" {
+		//fasthttp takes a query straight after the host as part of it, so an empty path is sent as /
+		v := *u
+		v.Fragment, v.RawFragment = "", ""
+		if v.Path == "" && v.Opaque == "" {
+			v.Path, v.RawPath = "/", ""
+		}
+		return v.String()
+	}
+	return u.String()
+}
@@ -0,0 +1,125 @@
+package jrc_test
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/cfoxon/jrc"
+)
+
+func TestURLSentVerbatim(t *testing.T) {
+	var mu sync.Mutex
+	var got []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		got = append(got, r.Method+" "+r.RequestURI)
+		mu.Unlock()
+		w.Write([]byte(`{"jsonrpc":"2.0","id":1,"result":true}`))
+	}))
+	defer ts.Close()
+	const path = "/rpc/v2//ns/../a%2Fb/?key=a%2Bb&k=%20"
+	for _, tr := range []jrc.Transport{nil, &jrc.NetHTTPTransport{}} {
+		got = nil
+		opts := []func(*jrc.Server) error{jrc.BareSingle(true), jrc.ReadOverGET("q", jrc.GETURLEncoded, "get")}
+		if tr != nil {
+			opts = append(opts, jrc.UseTransport(tr))
+		}
+		srv, err := jrc.NewServer(ts.URL+path+"#fragment", opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, method := range []string{"post", "get"} {
+			if _, err := srv.Exec(jrc.RpcRequest{JsonRpc: "2.0", Id: 1, Method: method}); err != nil {
+				t.Fatal(err)
+			}
+		}
+		srv.Close()
+		if len(got) != 2 || got[0] != "POST "+path || got[1][:len(path)+5] != "GET "+path+"&" {
+			t.Errorf("requests went to %q, want %s", got, path)
+		}
+	}
+}
+
+func TestWithPath(t *testing.T) {
+	mux := http.NewServeMux()
+	for _, path := range []string{"/jsonrpc", "/wallet"} {
+		path := path
+		mux.HandleFunc(path, func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(`[{"jsonrpc":"2.0","id":1,"result":"` + path + "?" + r.URL.RawQuery + `"}]`))
+		})
+	}
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+	srv, err := jrc.NewServer(ts.URL + "/jsonrpc?key=x")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer srv.Close()
+	for _, c := range []struct {
+		opts []jrc.CallOption
+		want string
+	}{
+		{nil, `"/jsonrpc?key=x"`},
+		{[]jrc.CallOption{jrc.WithPath("/wallet")}, `"/wallet?key=x"`},
+		{[]jrc.CallOption{jrc.WithPath("wallet?key=y")}, `"/wallet?key=y"`},
+	} {
+		resp, err := srv.Exec(jrc.RpcRequest{JsonRpc: "2.0", Id: 1, Method: "where"}, c.opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(resp.Result) != c.want {
+			t.Errorf("reached %s, want %s", resp.Result, c.want)
+		}
+	}
+}
+
+func TestFollowRedirects(t *testing.T) {
+	regional := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		if r.Method != http.MethodPost || len(b) == 0 || r.Header.Get("Authorization") != "" {
+			http.Error(w, "bad redirected request", http.StatusBadRequest)
+			return
+		}
+		w.Write([]byte(`[{"jsonrpc":"2.0","id":1,"result":"regional"}]`))
+	}))
+	defer regional.Close()
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/old":
+			if r.Header.Get("Authorization") == "" {
+				http.Error(w, "no credentials", http.StatusUnauthorized)
+				return
+			}
+			w.Header().Set("Location", "/rpc")
+			w.WriteHeader(http.StatusMovedPermanently)
+		case "/rpc":
+			w.Header().Set("Location", regional.URL+"/rpc")
+			w.WriteHeader(http.StatusTemporaryRedirect)
+		default:
+			w.Header().Set("Location", "/loop")
+			w.WriteHeader(http.StatusFound)
+		}
+	}))
+	defer ts.Close()
+	r := jrc.RpcRequest{JsonRpc: "2.0", Id: 1, Method: "where"}
+
+	srv, err := jrc.NewServer(ts.URL+"/old", jrc.FollowRedirects(5), jrc.Header("Authorization", "Bearer secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer srv.Close()
+	if resp, err := srv.Exec(r); err != nil || string(resp.Result) != `"regional"` {
+		t.Fatalf("got %+v, %v", resp, err)
+	}
+
+	looping, _ := jrc.NewServer(ts.URL+"/loop", jrc.FollowRedirects(3))
+	defer looping.Close()
+	var se *jrc.StatusError
+	if _, err := looping.Exec(r); !errors.As(err, &se) || se.StatusCode != http.StatusFound {
+		t.Errorf("got %v after too many redirects, want a 302 StatusError", err)
+	}
+}
@@ -0,0 +1,97 @@
+package jrc
+
+import (
+	"errors"
+
+	"github.com/cfoxon/jrc/internal/json"
+)
+
+//v1Request is the JSON RPC 1.0 form of a request, with no jsonrpc member and params always an array
+//  a null id makes it a notification
+type v1Request struct {
+	Method string      `json:"method"`
+	Params interface{} `json:"params"`
+	Id     ID          `json:"id"`
+}
+
+func v1Params(p interface{}) interface{} {
+	if p == nil {
+		return []interface{}{}
+	}
+	return p
+}
+
+//Version selects the protocol spoken to the Server, "2.0" (the default) or "1.0" for legacy daemons
+//  in 1.0 mode the jsonrpc member is left out, params default to an empty array,
+//  notifications carry a null id and each request is sent on its own as 1.0 has no batches
+func Version(v string) func(server *Server) error {
+	return func(srv *Server) error {
+		switch v {
+		case "1.0":
+			srv.v1 = true
+		case "2.0":
+			srv.v1 = false
+		default:
+			return errors.New("jrc: unsupported JSON RPC version " + v)
+		}
+		return nil
+	}
+}
+
+//BareSingle sends a request that goes in an HTTP call on its own, as Exec's does, as a bare object rather than in
+//  an array of one, for strict servers that refuse batches entirely; such requests are answered with a bare object
+//  as well, which is decoded the same way; it also applies to the last sub-batch of a batch when it holds one request
+func BareSingle(b bool) func(server *Server) error {
+	return func(srv *Server) error {
+		srv.bare = b
+		return nil
+	}
+}
+
+//marshalRequests turns each sub-batch into a request body in the form of the Server's protocol version
+func (srv *Server) marshalRequests(subs []SubBatch) ([][]byte, error) {
+	bodies := make([][]byte, 0, len(subs))
+	for _, sb := range subs {
+		var b []byte
+		var err error
+		if srv.v1 {
+			r := sb.Requests[0]
+			b, err = srv.codec.Marshal(v1Request{Method: r.Method, Params: v1Params(r.Params), Id: r.ID()})
+		} else if len(sb.Requests) == 1 && (srv.bare || srv.isUnbatched()) {
+			b, err = srv.codec.Marshal(sb.Requests[0])
+		} else {
+			b, err = srv.codec.Marshal(sb.Requests)
+		}
+		if err != nil {
+			return nil, err
+		}
+		bodies = append(bodies, b)
+	}
+	return bodies, nil
+}
+
+//marshalNotifications turns ns into request bodies of at most size notifications, for the Server's protocol version
+func (srv *Server) marshalNotifications(ns RPCNotifications, size int) ([][]byte, error) {
+	if !srv.v1 {
+		return marshalBatches(srv.codec, ns, size)
+	}
+	bodies := make([][]byte, 0, len(ns))
+	for _, n := range ns {
+		b, err := srv.codec.Marshal(v1Request{Method: n.Method, Params: v1Params(n.Params)})
+		if err != nil {
+			return nil, err
+		}
+		bodies = append(bodies, b)
+	}
+	return bodies, nil
+}
+
+//UnmarshalJSON implements json.Unmarshaler, also accepting the bare string errors some 1.0 servers send
+func (e *RpcError) UnmarshalJSON(b []byte) error {
+	if len(b) > 0 && b[0] == '"' {
+		*e = RpcError{}
+		return json.Unmarshal(b, &e.Message)
+	}
+	type rpcError RpcError
+	return json.Unmarshal(b, (*rpcError)(e))
+}
@@ -0,0 +1,157 @@
+package jrc
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/cfoxon/jrc/internal/json"
+)
+
+//recording is one exchange saved by Record, kept as the file named by the key of its requests
+type recording struct {
+	Request  json.RawMessage `json:"request"`
+	Response json.RawMessage `json:"response"`
+}
+
+//recorded is the part of a request the key of a recording is made from
+type recorded struct {
+	Id     ID              `json:"id"`
+	Method string          `json:"method"`
+	Params json.RawMessage `json:"params"`
+}
+
+//Record is a Middleware saving every request and the response to it as a file in dir, to be served back by a ReplayTransport
+//  files are named by the methods and params of the requests, so runs giving calls other ids share the recordings
+//  and a call made again overwrites the earlier one; notifications and failed calls are not recorded
+func Record(dir string) Middleware {
+	return func(next Handler) Handler {
+		return func(ctx context.Context, m *Message) ([]byte, error) {
+			b, err := next(ctx, m)
+			if err != nil || m.Notify {
+				return b, err
+			}
+			body, err := decodeBody(m.Header.Get("Content-Encoding"), m.Body, 0)
+			if err != nil {
+				return nil, err
+			}
+			_, key, err := recordingKey(body)
+			if err != nil {
+				return nil, err
+			}
+			rec, err := json.Marshal(recording{Request: body, Response: bytes.TrimSpace(b)})
+			if err != nil {
+				return nil, err
+			}
+			if err := os.MkdirAll(dir, 0o755); err != nil {
+				return nil, err
+			}
+			if err := os.WriteFile(filepath.Join(dir, key+".json"), rec, 0o644); err != nil {
+				return nil, err
+			}
+			return b, nil
+		}
+	}
+}
+
+//recordingKey parses the requests in body and returns them along with the name of their recording
+func recordingKey(body []byte) ([]recorded, string, error) {
+	body = bytes.TrimSpace(body)
+	batch := len(body) > 0 && body[0] == '['
+	var rs []recorded
+	var err error
+	if batch {
+		err = json.Unmarshal(body, &rs)
+	} else {
+		rs = make([]recorded, 1)
+		err = json.Unmarshal(body, &rs[0])
+	}
+	if err != nil {
+		return nil, "", errors.New("jrc: can't parse request for recording: " + err.Error())
+	}
+	h := sha256.New()
+	if batch {
+		h.Write([]byte("["))
+	}
+	for _, r := range rs {
+		h.Write([]byte(r.Method))
+		h.Write([]byte{0})
+		h.Write(bytes.TrimSpace(r.Params))
+		h.Write([]byte{0})
+	}
+	return rs, hex.EncodeToString(h.Sum(nil)), nil
+}
+
+//ReplayTransport answers with the responses saved by Record instead of calling a server, for tests without a live node
+//  the ids of the recorded responses are changed to those of the requests being answered
+type ReplayTransport struct {
+	Dir string //the directory given to Record
+}
+
+//RoundTrip implements Transport, returning an error for requests with no recording
+func (t *ReplayTransport) RoundTrip(ctx context.Context, m *Message) ([]byte, error) {
+	if m.Notify {
+		return nil, nil
+	}
+	body, err := decodeBody(m.Header.Get("Content-Encoding"), m.Body, 0)
+	if err != nil {
+		return nil, err
+	}
+	rs, key, err := recordingKey(body)
+	if err != nil {
+		return nil, err
+	}
+	b, err := os.ReadFile(filepath.Join(t.Dir, key+".json"))
+	if os.IsNotExist(err) {
+		methods := make([]string, len(rs))
+		for i, r := range rs {
+			methods[i] = r.Method
+		}
+		return nil, errors.New("jrc: no recording in " + t.Dir + " of a call to " + strings.Join(methods, ", "))
+	}
+	if err != nil {
+		return nil, err
+	}
+	var rec recording
+	if err := json.Unmarshal(b, &rec); err != nil {
+		return nil, errors.New("jrc: corrupt recording " + key + ": " + err.Error())
+	}
+	was, _, err := recordingKey(rec.Request)
+	if err != nil || len(was) != len(rs) {
+		return nil, errors.New("jrc: corrupt recording " + key)
+	}
+	ids := make(map[ID]ID, len(rs))
+	for i := range rs {
+		ids[was[i].Id] = rs[i].Id
+	}
+	return reassignIDs(rec.Response, ids)
+}
+
+//reassignIDs maps the ids of the response or batch of responses in b through ids
+func reassignIDs(b []byte, ids map[ID]ID) ([]byte, error) {
+	if len(b) > 0 && b[0] == '[' {
+		var resps []RpcResponse
+		if err := json.Unmarshal(b, &resps); err != nil {
+			return nil, err
+		}
+		for i := range resps {
+			if id, ok := ids[resps[i].ID]; ok {
+				resps[i].ID = id
+			}
+		}
+		return json.Marshal(resps)
+	}
+	var resp RpcResponse
+	if err := json.Unmarshal(b, &resp); err != nil {
+		return nil, err
+	}
+	if id, ok := ids[resp.ID]; ok {
+		resp.ID = id
+	}
+	return json.Marshal(resp)
+}
@@ -0,0 +1,386 @@
+package jrc
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/cfoxon/jrc/internal/json"
+	"golang.org/x/net/websocket"
+)
+
+const (
+	maxEarlyEvents     = 1000 //notifications held for subscriptions whose id has not been seen yet
+	unsubscribeTimeout = 10 * time.Second
+)
+
+//wsTransport speaks JSON RPC over a WebSocket, a streamTransport whose connections are WebSockets
+//  it passes the notifications for subscriptions made with Subscribe to them, and the rest to HandleIncoming
+type wsTransport struct {
+	*streamTransport
+	srv    *Server
+	u      *url.URL
+	ctx    context.Context //done once the transport is closed, stopping resubscription
+	cancel context.CancelFunc
+
+	subMu     sync.Mutex
+	resub     RetryPolicy
+	subs      map[ID]*wsSub
+	early     map[ID][]Event //notifications that came before the response to their subscribe call was handled
+	earlyN    int
+	earlyConn *streamConn //the connection the notifications in early came over
+}
+
+//wsSub is a Subscription along with the connection it lives on and the call that made it
+type wsSub struct {
+	s           *Subscription
+	c           *streamConn
+	r           *RpcRequest
+	unsubscribe string
+}
+
+//wsMessages carries one JSON RPC message per WebSocket message
+type wsMessages struct {
+	*websocket.Conn
+}
+
+func (w wsMessages) readMessage(limit int) ([]byte, error) {
+	var b []byte
+	err := websocket.Message.Receive(w.Conn, &b)
+	if err == websocket.ErrFrameTooLarge {
+		return nil, ErrResponseTooLarge
+	}
+	if limit > 0 && len(b) > limit {
+		return nil, ErrResponseTooLarge
+	}
+	return b, err
+}
+
+func (w wsMessages) writeMessage(b []byte) error {
+	return websocket.Message.Send(w.Conn, string(b))
+}
+
+func newWSTransport(srv *Server, u *url.URL) *wsTransport {
+	t := &wsTransport{srv: srv, u: u, subs: make(map[ID]*wsSub), early: make(map[ID][]Event)}
+	t.ctx, t.cancel = context.WithCancel(context.Background())
+	t.streamTransport = dialStream(t.dial, NewlineFraming)
+	t.notify = t.notification
+	t.opened = func(c *streamConn) {
+		go t.watch(c)
+	}
+	return t
+}
+
+//dial opens a WebSocket to the endpoint, sending h with the handshake
+func (t *wsTransport) dial(ctx context.Context, h http.Header) (io.ReadWriteCloser, error) {
+	origin := url.URL{Scheme: "http", Host: t.u.Host}
+	port := "80"
+	if t.u.Scheme == "wss" {
+		origin.Scheme, port = "https", "443"
+	}
+	cfg, err := websocket.NewConfig(t.u.String(), origin.String())
+	if err != nil {
+		return nil, err
+	}
+	cfg.Header = h.Clone()
+	if cfg.Header == nil {
+		cfg.Header = http.Header{}
+	}
+	addr := t.u.Host
+	if t.u.Port() == "" {
+		addr = net.JoinHostPort(t.u.Hostname(), port)
+	}
+	var conn net.Conn
+	if t.srv.hc.Dial != nil {
+		//DialTimeout, Dial or Proxy
+		conn, err = t.srv.hc.Dial(addr)
+	} else {
+		var d net.Dialer
+		conn, err = d.DialContext(ctx, "tcp", addr)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if t.u.Scheme == "wss" {
+		tc := &tls.Config{}
+		if t.srv.hc.TLSConfig != nil {
+			tc = t.srv.hc.TLSConfig.Clone()
+		}
+		if tc.ServerName == "" {
+			tc.ServerName = t.u.Hostname()
+		}
+		tlsConn := tls.Client(conn, tc)
+		if err := tlsConn.HandshakeContext(ctx); err != nil {
+			conn.Close()
+			return nil, err
+		}
+		conn = tlsConn
+	}
+	deadline, _ := ctx.Deadline()
+	conn.SetDeadline(deadline)
+	ws, err := websocket.NewClient(cfg, conn)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	conn.SetDeadline(time.Time{})
+	if t.srv.maxResp > 0 {
+		ws.MaxPayloadBytes = t.srv.maxResp
+	}
+	return wsMessages{ws}, nil
+}
+
+//notification passes a notification for a subscription to it, returning false for any other notification
+func (t *wsTransport) notification(c *streamConn, method string, params json.RawMessage) bool {
+	var p struct {
+		Subscription ID              `json:"subscription"`
+		Result       json.RawMessage `json:"result"`
+	}
+	if json.Unmarshal(params, &p) != nil || p.Subscription.IsNull() {
+		return false
+	}
+	ev := Event{Method: method, Params: params, Result: p.Result}
+	t.subMu.Lock()
+	sub, ok := t.subs[p.Subscription]
+	if !ok || sub.c != c {
+		if t.earlyConn != c {
+			t.early, t.earlyN, t.earlyConn = make(map[ID][]Event), 0, c
+		}
+		if t.earlyN < maxEarlyEvents {
+			t.early[p.Subscription] = append(t.early[p.Subscription], ev)
+			t.earlyN++
+		}
+		t.subMu.Unlock()
+		return true
+	}
+	t.subMu.Unlock()
+	sub.s.deliver(ev)
+	return true
+}
+
+//watch ends the subscriptions on c once it has closed, or makes them again on a new connection if Resubscribe is set
+func (t *wsTransport) watch(c *streamConn) {
+	<-c.dead
+	err := c.err
+	var lost []*wsSub
+	t.subMu.Lock()
+	for id, sub := range t.subs {
+		if sub.c == c {
+			delete(t.subs, id)
+			lost = append(lost, sub)
+		}
+	}
+	if t.earlyConn == c {
+		t.early, t.earlyN, t.earlyConn = make(map[ID][]Event), 0, nil
+	}
+	p := t.resub
+	t.subMu.Unlock()
+	if len(lost) > 0 && p.Max > 0 && err != ErrClosed {
+		lost, err = t.resubscribe(lost, p, err)
+	}
+	if err != ErrClosed {
+		err = &TransportError{Err: err}
+	}
+	for _, sub := range lost {
+		sub.s.end(err)
+	}
+}
+
+//resubscribe makes the subscriptions in lost again, waiting before each attempt as p says, returning those it
+//  could not make along with the last error
+func (t *wsTransport) resubscribe(lost []*wsSub, p RetryPolicy, err error) ([]*wsSub, error) {
+	for attempt := 0; len(lost) > 0 && attempt < p.Max; attempt++ {
+		d := p.delay(attempt)
+		t.srv.log(LogWarn, "jrc: resubscribing", "attempt", attempt+1, "delay", d, "subscriptions", len(lost), "error", err)
+		if !sleep(t.ctx, d) {
+			return lost, ErrClosed
+		}
+		for i, sub := range lost {
+			if sub.s.isEnded() {
+				lost[i] = nil
+				continue
+			}
+			r := *sub.r
+			r.SetID(t.srv.ids.NextID())
+			var id ID
+			var c *streamConn
+			if id, c, err = t.open(t.ctx, &r); err != nil {
+				if _, ok := err.(*RpcError); ok {
+					//the server refused it, which trying again won't change
+					sub.s.end(err)
+					lost[i] = nil
+					continue
+				}
+				//the connection failed again, so the rest would too
+				break
+			}
+			t.subMu.Lock()
+			if c.isDead() {
+				//its watch may have been through the subscriptions of c already
+				t.subMu.Unlock()
+				err = c.err
+				break
+			}
+			lost[i] = nil
+			if sub.s.isEnded() {
+				//Unsubscribe was called meanwhile
+				t.subMu.Unlock()
+				t.end(id, sub.unsubscribe)
+				continue
+			}
+			sub.c = c
+			sub.s.setID(id)
+			sub.s.deliver(Event{Gap: true})
+			t.add(id, sub)
+			t.subMu.Unlock()
+		}
+		left := lost[:0]
+		for _, sub := range lost {
+			if sub != nil {
+				left = append(left, sub)
+			}
+		}
+		lost = left
+	}
+	if t.ctx.Err() != nil {
+		err = ErrClosed
+	}
+	return lost, err
+}
+
+//open makes the call r that starts a subscription, returning the id the server gave it and the connection it is on
+func (t *wsTransport) open(ctx context.Context, r *RpcRequest) (ID, *streamConn, error) {
+	resp, c, err := t.call(ctx, r)
+	if err != nil {
+		return ID{}, nil, err
+	}
+	var id ID
+	if err := json.Unmarshal(resp.Result, &id); err != nil || id.IsNull() {
+		return ID{}, nil, &ParseError{Body: resp.Result, Err: errors.New("jrc: invalid subscription id")}
+	}
+	return id, c, nil
+}
+
+//add registers sub under id, passing it the notifications that came for id ahead of the response; the caller holds subMu
+func (t *wsTransport) add(id ID, sub *wsSub) {
+	t.subs[id] = sub
+	if t.earlyConn == sub.c {
+		//delivered with subMu held, so they are ahead of the notifications read from now on
+		for _, ev := range t.early[id] {
+			sub.s.deliver(ev)
+		}
+		t.earlyN -= len(t.early[id])
+		delete(t.early, id)
+	}
+}
+
+func (t *wsTransport) subscribe(ctx context.Context, r *RpcRequest, unsubscribe string) (*Subscription, error) {
+	id, c, err := t.open(ctx, r)
+	if err != nil {
+		return nil, err
+	}
+	sub := &wsSub{c: c, r: r, unsubscribe: unsubscribe}
+	sub.s = newSubscription(id, func() error {
+		return t.unsubscribe(sub)
+	})
+	t.subMu.Lock()
+	defer t.subMu.Unlock()
+	if c.isDead() {
+		//watch may have been through the subscriptions of c already
+		sub.s.end(&TransportError{Err: c.err})
+		return nil, sub.s.Err()
+	}
+	t.add(id, sub)
+	return sub.s, nil
+}
+
+//unsubscribe tells the server to end sub, unless the connection it was made on has gone
+func (t *wsTransport) unsubscribe(sub *wsSub) error {
+	t.subMu.Lock()
+	id := sub.s.ID()
+	if t.subs[id] != sub {
+		t.subMu.Unlock()
+		return nil
+	}
+	delete(t.subs, id)
+	t.subMu.Unlock()
+	return t.end(id, sub.unsubscribe)
+}
+
+//end calls method to end subscription id
+func (t *wsTransport) end(id ID, method string) error {
+	ctx, cancel := context.WithTimeout(t.ctx, unsubscribeTimeout)
+	defer cancel()
+	r := &RpcRequest{JsonRpc: "2.0", Method: method, Params: []interface{}{id}}
+	r.SetID(t.srv.ids.NextID())
+	_, _, err := t.call(ctx, r)
+	return err
+}
+
+//Close closes the connection, ending its subscriptions with ErrClosed
+func (t *wsTransport) Close() error {
+	t.cancel()
+	return t.streamTransport.Close()
+}
+
+func (srv *Server) setResubscribe(p RetryPolicy) error {
+	if err := p.check(); err != nil {
+		return err
+	}
+	found := false
+	for _, ep := range srv.endpoints {
+		if t, ok := ep.tr.(*wsTransport); ok {
+			t.subMu.Lock()
+			t.resub = p
+			t.subMu.Unlock()
+			found = true
+		}
+	}
+	if !found {
+		return errors.New("jrc: Resubscribe only applies to ws:// and wss:// servers")
+	}
+	return nil
+}
+
+//Resubscribe keeps subscriptions going when their connection drops, dialing again after the delays of p and
+//  making each subscription again with the call that first made it; an Event with Gap set then tells the consumer
+//  that notifications may have been missed; a subscription ends when p.Max attempts have failed or the server
+//  refuses to make it again; the zero RetryPolicy, the default, ends subscriptions with their connection
+func Resubscribe(p RetryPolicy) func(server *Server) error {
+	return func(srv *Server) error {
+		return srv.setResubscribe(p)
+	}
+}
+
+//call makes the single call r, returning an RpcError in the response as the error, and the connection it went over
+func (t *wsTransport) call(ctx context.Context, r *RpcRequest) (*RpcResponse, *streamConn, error) {
+	body, err := json.Marshal(r)
+	if err != nil {
+		return nil, nil, err
+	}
+	hs, err := t.srv.callHeaders(ctx, &callConfig{})
+	if err != nil {
+		return nil, nil, err
+	}
+	b, c, err := t.roundTrip(ctx, &Message{URL: t.u.String(), Header: hs, Body: body, MaxResponseSize: t.srv.maxResp})
+	if err != nil {
+		return nil, c, err
+	}
+	resps, err := parseBatch(JSONCodec{}, [][]byte{b}, nil)
+	if err != nil {
+		return nil, c, err
+	}
+	if len(resps) != 1 {
+		return nil, c, &ParseError{Body: b, Err: errors.New("jrc: expected one response")}
+	}
+	if resps[0].Error != nil {
+		return nil, c, resps[0].Error
+	}
+	return &resps[0], c, nil
+}
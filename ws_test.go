@@ -0,0 +1,217 @@
+package jrc_test
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cfoxon/jrc"
+	"github.com/goccy/go-json"
+	"golang.org/x/net/websocket"
+)
+
+//wsServer answers every call with its method name, except eth_subscribe, which starts subscription 0xa
+//  with one notification ahead of the response and n-1 after it, and eth_unsubscribe, which is sent on unsubscribed
+func wsServer(t *testing.T, n int, unsubscribed chan<- string) string {
+	t.Helper()
+	ts := httptest.NewServer(websocket.Handler(func(ws *websocket.Conn) {
+		notify := func(i int) {
+			websocket.Message.Send(ws, `{"jsonrpc":"2.0","method":"eth_subscription","params":{"subscription":"0xa","result":`+strconv.Itoa(i)+`}}`)
+		}
+		for {
+			var msg []byte
+			if websocket.Message.Receive(ws, &msg) != nil {
+				return
+			}
+			q := call(msg)
+			reply := func(result string) {
+				b := `{"jsonrpc":"2.0","id":` + string(q.Id) + `,"result":` + result + `}`
+				if msg[0] == '[' {
+					b = "[" + b + "]"
+				}
+				websocket.Message.Send(ws, b)
+			}
+			switch q.Method {
+			case "eth_subscribe":
+				notify(0)
+				reply(`"0xa"`)
+				for i := 1; i < n; i++ {
+					notify(i)
+				}
+			case "eth_unsubscribe":
+				var p struct{ Params []string }
+				json.Unmarshal(msg, &p)
+				unsubscribed <- p.Params[0]
+				reply("true")
+			default:
+				reply(`"` + q.Method + `"`)
+			}
+		}
+	}))
+	t.Cleanup(ts.Close)
+	return "ws" + strings.TrimPrefix(ts.URL, "http")
+}
+
+func TestSubscribe(t *testing.T) {
+	unsubscribed := make(chan string, 1)
+	srv, err := jrc.NewServer(wsServer(t, 100, unsubscribed))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer srv.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	sub, err := srv.Subscribe(ctx, "eth_subscribe", "newHeads")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sub.ID() != jrc.StringID("0xa") {
+		t.Fatalf("subscription id %v", sub.ID())
+	}
+	//the notification that came ahead of the response is kept, and all arrive in order
+	got := 0
+	for ev := range sub.C {
+		if string(ev.Result) != strconv.Itoa(got) {
+			t.Fatalf("event %d has result %s", got, ev.Result)
+		}
+		got++
+		if got == 50 {
+			if res, err := srv.Call(ctx, "net_version"); err != nil || string(res) != `"net_version"` {
+				t.Fatalf("call while subscribed: %s, %v", res, err)
+			}
+		}
+		if got == 100 {
+			if err := sub.Unsubscribe(); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	if got != 100 || sub.Err() != nil {
+		t.Fatalf("%d events, ended with %v", got, sub.Err())
+	}
+	if id := <-unsubscribed; id != "0xa" {
+		t.Errorf("unsubscribed %q", id)
+	}
+}
+
+func TestSubscriptionEndsWithConnection(t *testing.T) {
+	var mu sync.Mutex
+	var conns []*websocket.Conn
+	ts := httptest.NewServer(websocket.Handler(func(ws *websocket.Conn) {
+		mu.Lock()
+		conns = append(conns, ws)
+		mu.Unlock()
+		for {
+			var msg []byte
+			if websocket.Message.Receive(ws, &msg) != nil {
+				return
+			}
+			websocket.Message.Send(ws, `{"jsonrpc":"2.0","id":`+string(call(msg).Id)+`,"result":"0xb"}`)
+		}
+	}))
+	defer ts.Close()
+	srv, _ := jrc.NewServer("ws" + strings.TrimPrefix(ts.URL, "http"))
+	defer srv.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	sub, err := srv.Subscribe(ctx, "eth_subscribe", "logs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	mu.Lock()
+	conns[0].Close()
+	mu.Unlock()
+	for range sub.C {
+	}
+	var te *jrc.TransportError
+	if !errors.As(sub.Err(), &te) {
+		t.Errorf("subscription ended with %v, want a TransportError", sub.Err())
+	}
+	//the next call dials again
+	if _, err := srv.Call(ctx, "net_version"); err != nil {
+		t.Fatal(err)
+	}
+
+	sub, _ = srv.Subscribe(ctx, "eth_subscribe", "logs")
+	srv.Close()
+	for range sub.C {
+	}
+	if !errors.Is(sub.Err(), jrc.ErrClosed) {
+		t.Errorf("subscription ended with %v after Close, want ErrClosed", sub.Err())
+	}
+}
+
+func TestSubscribeNeedsWebSocket(t *testing.T) {
+	srv, _ := jrc.NewServer("http://127.0.0.1:1")
+	defer srv.Close()
+	if _, err := srv.Subscribe(context.Background(), "eth_subscribe", "newHeads"); err == nil {
+		t.Fatal("Subscribe over HTTP succeeded")
+	}
+}
+
+func TestResubscribe(t *testing.T) {
+	var mu sync.Mutex
+	var conns []*websocket.Conn
+	ts := httptest.NewServer(websocket.Handler(func(ws *websocket.Conn) {
+		mu.Lock()
+		conns = append(conns, ws)
+		id := `"0x` + strconv.Itoa(len(conns)) + `"`
+		mu.Unlock()
+		for {
+			var msg []byte
+			if websocket.Message.Receive(ws, &msg) != nil {
+				return
+			}
+			websocket.Message.Send(ws, `{"jsonrpc":"2.0","id":`+string(call(msg).Id)+`,"result":`+id+`}`)
+			websocket.Message.Send(ws, `{"jsonrpc":"2.0","method":"eth_subscription","params":{"subscription":`+id+`,"result":`+id+`}}`)
+		}
+	}))
+	defer ts.Close()
+	srv, _ := jrc.NewServer("ws"+strings.TrimPrefix(ts.URL, "http"), jrc.Resubscribe(jrc.RetryPolicy{Max: 3, Base: 10 * time.Millisecond}))
+	defer srv.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	sub, err := srv.Subscribe(ctx, "eth_subscribe", "newHeads")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ev := <-sub.C; string(ev.Result) != `"0x1"` {
+		t.Fatalf("first event %+v", ev)
+	}
+	mu.Lock()
+	conns[0].Close()
+	mu.Unlock()
+	//the consumer is told of the gap before the notifications of the new subscription
+	if ev := <-sub.C; !ev.Gap {
+		t.Fatalf("got %+v after the connection dropped, want a gap", ev)
+	}
+	if ev := <-sub.C; string(ev.Result) != `"0x2"` {
+		t.Fatalf("event after resubscribing %+v", ev)
+	}
+	if sub.ID() != jrc.StringID("0x2") {
+		t.Errorf("subscription id %v after resubscribing", sub.ID())
+	}
+
+	//subscriptions end once the server can't be reached for all the attempts
+	ts.Listener.Close()
+	mu.Lock()
+	conns[1].Close()
+	mu.Unlock()
+	for range sub.C {
+	}
+	var te *jrc.TransportError
+	if !errors.As(sub.Err(), &te) {
+		t.Errorf("subscription ended with %v, want a TransportError", sub.Err())
+	}
+	if _, err := jrc.NewServer(ts.URL, jrc.Resubscribe(jrc.RetryPolicy{Max: 1})); err == nil {
+		t.Error("Resubscribe accepted for an HTTP server")
+	}
+}